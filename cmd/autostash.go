@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/ziriraha/odv/lib"
+	"github.com/ziriraha/odv/views"
+)
+
+type autostash struct {
+	commit string // "" when there was nothing to stash
+	ref    string // set when the stash was left behind
+	popErr error
+}
+
+func addAutostashFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("autostash", lib.GetConfig().Autostash, "Stash the local changes before and restore them after.")
+}
+
+// withAutostash stashes the local changes of repo, runs op and restores them,
+// or only runs op when disabled. The stash is left in place when op stops a
+// rebase on conflicts, or when restoring it conflicts; render tells the user.
+func withAutostash(ctx context.Context, enabled bool, repo *lib.Repository, operation string, op func() error) (*autostash, error) {
+	if !enabled {
+		return nil, op()
+	}
+	message := fmt.Sprintf("odv %s: autostash on '%s'", operation, repo.GetCurrentBranch(ctx))
+	commit, err := repo.Stash(ctx, message, false)
+	if err != nil {
+		return nil, fmt.Errorf("autostash failed: %w", err)
+	}
+	stash := &autostash{commit: commit}
+
+	err = op()
+	if commit == "" {
+		return stash, err
+	}
+	// Restore the changes even when op was cancelled or timed out, each git
+	// command still gets its own timeout.
+	ctx = context.WithoutCancel(ctx)
+	if repo.IsRebasing(ctx) {
+		stash.ref, _ = repo.StashRef(ctx, commit)
+		return stash, err
+	}
+	if stash.popErr = repo.StashPop(ctx, commit); stash.popErr != nil {
+		stash.ref, _ = repo.StashRef(ctx, commit)
+	}
+	return stash, err
+}
+
+func (s *autostash) render() string {
+	switch {
+	case s == nil || s.commit == "":
+		return ""
+	case s.popErr != nil && s.ref == "":
+		return fmt.Sprintf("   %s\n", views.ErrorStyle.Render(fmt.Sprintf("failed to restore the local changes: %v", s.popErr)))
+	case s.popErr != nil:
+		return fmt.Sprintf("   %s\n", views.WarningStyle.Render(fmt.Sprintf(
+			"restoring the local changes conflicted, resolve them then run 'git stash drop %s'", s.ref)))
+	case s.ref != "":
+		return fmt.Sprintf("   %s\n", views.WarningStyle.Render(fmt.Sprintf(
+			"local changes are kept in %s, run 'git stash pop %s' once the rebase is done", s.ref, s.ref)))
+	default:
+		return fmt.Sprintf("   %s\n", views.FaintStyle.Render("local changes stashed and restored"))
+	}
+}
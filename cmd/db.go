@@ -1,12 +1,36 @@
 package cmd
 
 import (
+	"fmt"
 	"os"
+	"runtime"
+	"sync"
+	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/ziriraha/odv/lib"
+	"github.com/ziriraha/odv/views"
 )
 
+// dbRecord is the JSON output of the db commands acting on databases.
+type dbRecord struct {
+	Name        string `json:"name"`
+	Action      string `json:"action,omitempty"`
+	Branch      string `json:"branch,omitempty"`
+	Source      string `json:"source,omitempty"`
+	File        string `json:"file,omitempty"`
+	Neutralized bool   `json:"neutralized,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// dbInfoRecord is the JSON output of db list --long, Error is set when the
+// database could not be read.
+type dbInfoRecord struct {
+	*lib.DBInfo
+	Error string `json:"error,omitempty"`
+}
+
 var dbCmd = &cobra.Command{
 	Use:   "db",
 	Short: "Database management for Odoo.",
@@ -24,20 +48,26 @@ var dbDropCmd = &cobra.Command{
 			if len(args) == 1 {
 				prefix = args[0]
 			}
-			dbsToDelete, err := lib.ListDBs(prefix)
+			dbsToDelete, err := lib.ListDBs(cmd.Context(), prefix)
 			if err != nil {
 				cmd.PrintErrln("Failed to list databases:", err)
 				os.Exit(1)
 			}
+			records := []dbRecord{}
 			for _, dbname := range dbsToDelete {
-				err := lib.DropDB(dbname)
+				record := dbRecord{Name: dbname, Action: "dropped"}
+				err := lib.DropDB(cmd.Context(), dbname)
 				if err != nil {
+					record.Error = err.Error()
 					cmd.PrintErrf("Failed to drop database %s: %v\n", dbname, err)
-				} else {
+				} else if !isJSONOutput(cmd) {
 					cmd.Printf("Dropped database: %s\n", dbname)
 				}
+				records = append(records, record)
 			}
-			if len(dbsToDelete) == 0 {
+			if isJSONOutput(cmd) {
+				printJSON(cmd, records)
+			} else if len(dbsToDelete) == 0 {
 				cmd.Printf("No databases found with prefix '%s'.\n", prefix)
 			}
 		} else {
@@ -46,14 +76,59 @@ var dbDropCmd = &cobra.Command{
 				os.Exit(1)
 			}
 			dbname := args[0]
-			err := lib.DropDB(dbname)
+			err := lib.DropDB(cmd.Context(), dbname)
 			if err != nil {
 				cmd.PrintErrf("Failed to drop database %s: %v\n", dbname, err)
+			} else {
+				printResult(cmd, dbRecord{Name: dbname, Action: "dropped"}, "Dropped database: %s\n", dbname)
 			}
 		}
 	},
 }
 
+var dbCreateCmd = &cobra.Command{
+	Use:   "create [dbname]",
+	Short: "Creates an empty database linked to the current branch.",
+	Long:  "Creates an empty database and links it to the current branch. The name defaults to the database already linked to the branch, or the db prefix followed by the branch name.",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		branch := lib.GetCurrentBranch(cmd.Context())
+		dbname := lib.GetBranchDB(branch)
+		if len(args) == 1 {
+			dbname = args[0]
+		}
+		err := lib.CreateDB(cmd.Context(), dbname)
+		if err != nil {
+			cmd.PrintErrf("Failed to create database %s: %v\n", dbname, err)
+			os.Exit(1)
+		}
+		if err := lib.LinkDB(branch, dbname); err != nil {
+			cmd.PrintErrf("Failed to link database %s to '%s': %v\n", dbname, branch, err)
+			os.Exit(1)
+		}
+		printResult(cmd, dbRecord{Name: dbname, Action: "created", Branch: branch}, "Created database %s for '%s'\n", dbname, branch)
+	},
+}
+
+var dbLinkCmd = &cobra.Command{
+	Use:   "link <dbname> [branch]",
+	Short: "Links a database to a branch.",
+	Long:  "Records that the database belongs to the branch (the current branch by default). Linked databases are used by default by run, and offered for dropping when the branch is deleted.",
+	Args:  cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		dbname := args[0]
+		branch := lib.GetCurrentBranch(cmd.Context())
+		if len(args) == 2 {
+			branch = args[1]
+		}
+		if err := lib.LinkDB(branch, dbname); err != nil {
+			cmd.PrintErrf("Failed to link database %s to '%s': %v\n", dbname, branch, err)
+			os.Exit(1)
+		}
+		printResult(cmd, dbRecord{Name: dbname, Action: "linked", Branch: branch}, "Linked database %s to '%s'\n", dbname, branch)
+	},
+}
+
 var dbDuplicateCmd = &cobra.Command{
 	Use:   "duplicate <source_db> <new_db>",
 	Short: "Duplicates an existing database.",
@@ -62,13 +137,18 @@ var dbDuplicateCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		sourceDB := args[0]
 		newDB := args[1]
-		err := lib.DuplicateDB(sourceDB, newDB)
+		err := lib.DuplicateDB(cmd.Context(), sourceDB, newDB)
 		if err != nil {
 			cmd.PrintErrf("Failed to duplicate database from %s to %s: %v\n", sourceDB, newDB, err)
 			os.Exit(1)
-		} else {
+		} else if !isJSONOutput(cmd) {
 			cmd.Printf("Successfully duplicated database from %s to %s\n", sourceDB, newDB)
 		}
+		record := dbRecord{Name: newDB, Action: "duplicated", Source: sourceDB}
+		record.Neutralized = neutralizeAfter(cmd, newDB)
+		if isJSONOutput(cmd) {
+			printJSON(cmd, record)
+		}
 	},
 }
 
@@ -82,27 +162,226 @@ var dbListCmd = &cobra.Command{
 		if len(args) == 1 {
 			prefix = args[0]
 		}
-		dbs, err := lib.ListDBs(prefix)
+		dbs, err := lib.ListDBs(cmd.Context(), prefix)
 		if err != nil {
 			cmd.PrintErrln("Failed to list databases:", err)
 			os.Exit(1)
 		}
-		if len(dbs) == 0 {
+		// Scripts get an empty JSON list instead.
+		if len(dbs) == 0 && !isJSONOutput(cmd) {
 			cmd.Printf("No databases found with the '%s' prefix.\n", prefix)
 			return
 		}
-		for _, db := range dbs {
-			cmd.Printf("%s\n", db)
+		long, _ := cmd.Flags().GetBool("long")
+		if !long && isJSONOutput(cmd) {
+			records := make([]dbRecord, len(dbs))
+			for i, db := range dbs {
+				records[i] = dbRecord{Name: db}
+			}
+			printJSON(cmd, records)
+			return
+		}
+		if !long {
+			for _, db := range dbs {
+				cmd.Printf("%s\n", db)
+			}
+			return
+		}
+
+		// Each database takes a connection, keep them to as many as there are CPUs.
+		records := make([]dbInfoRecord, len(dbs))
+		sem := make(chan struct{}, runtime.GOMAXPROCS(0))
+		var wg sync.WaitGroup
+		for i, db := range dbs {
+			wg.Go(func() {
+				sem <- struct{}{}
+				defer func() { <-sem }()
+				info, err := lib.GetDBInfo(cmd.Context(), db)
+				if err != nil {
+					records[i] = dbInfoRecord{DBInfo: &lib.DBInfo{Name: db}, Error: err.Error()}
+					return
+				}
+				records[i] = dbInfoRecord{DBInfo: info}
+			})
+		}
+		wg.Wait()
+		if isJSONOutput(cmd) {
+			printJSON(cmd, records)
+			return
+		}
+
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tVERSION\tMODULES\tSIZE\tMODIFIED")
+		for _, record := range records {
+			info := record.DBInfo
+			if record.Error != "" {
+				fmt.Fprintf(w, "%s\terror: %s\n", info.Name, record.Error)
+				continue
+			}
+			version, modules := "-", "-"
+			if info.IsOdoo {
+				version, modules = info.Version, fmt.Sprint(info.InstalledModules)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", info.Name, version, modules, formatSize(info.Size+info.FilestoreSize), formatTime(info.LastModified))
+		}
+		w.Flush()
+	},
+}
+
+var dbInfoCmd = &cobra.Command{
+	Use:   "info <dbname>",
+	Short: "Shows information about a database.",
+	Long:  "Shows the Odoo version, installed modules, size on disk (database and filestore) and last modification of a database.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		info, err := lib.GetDBInfo(cmd.Context(), args[0])
+		if err != nil {
+			cmd.PrintErrln("Failed to read database info:", err)
+			os.Exit(1)
+		}
+		if isJSONOutput(cmd) {
+			printJSON(cmd, info)
+			return
+		}
+
+		printField := func(label string, value string) {
+			if value == "" {
+				value = views.FaintStyle.Render("-")
+			}
+			cmd.Printf("%s %s\n", views.BoldStyle.Render(fmt.Sprintf("%-10s", label+":")), value)
+		}
+		printField("name", info.Name)
+		if !info.IsOdoo {
+			printField("version", views.WarningStyle.Render("not an Odoo database"))
+		} else {
+			printField("version", info.Version)
+			printField("modules", fmt.Sprintf("%d installed", info.InstalledModules))
+		}
+		printField("size", fmt.Sprintf("%s (database %s, filestore %s)",
+			formatSize(info.Size+info.FilestoreSize), formatSize(info.Size), formatSize(info.FilestoreSize)))
+		printField("modified", formatTime(info.LastModified))
+		if info.IsOdoo {
+			printField("created", info.CreateDate)
+			printField("uuid", info.UUID)
+			printField("base url", info.BaseURL)
+		}
+	},
+}
+
+func formatSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+var dbBackupCmd = &cobra.Command{
+	Use:   "backup <dbname> [file]",
+	Short: "Backs up a database to a zip file.",
+	Long:  "Dumps the database and its filestore to a zip file in the same format as Odoo's database manager. The file defaults to '<dbname>_<timestamp>.zip' in the current directory.",
+	Args:  cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		dbname := args[0]
+		zipPath := fmt.Sprintf("%s_%s.zip", dbname, time.Now().Format("2006-01-02_15-04-05"))
+		if len(args) == 2 {
+			zipPath = args[1]
+		}
+		err := lib.BackupDB(cmd.Context(), dbname, zipPath)
+		if err != nil {
+			cmd.PrintErrf("Failed to back up database %s: %v\n", dbname, err)
+			os.Exit(1)
+		}
+		printResult(cmd, dbRecord{Name: dbname, Action: "backed up", File: zipPath}, "Backed up database %s to %s\n", dbname, zipPath)
+	},
+}
+
+var dbRestoreCmd = &cobra.Command{
+	Use:   "restore <file> <new_db>",
+	Short: "Restores a database from a zip file.",
+	Long:  "Creates a new database and filestore from a zip backup made by odv or by Odoo's database manager. The new database must not already exist.",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		zipPath := args[0]
+		newDB := args[1]
+		err := lib.RestoreDB(cmd.Context(), zipPath, newDB)
+		if err != nil {
+			cmd.PrintErrf("Failed to restore %s into %s: %v\n", zipPath, newDB, err)
+			os.Exit(1)
+		}
+		if !isJSONOutput(cmd) {
+			cmd.Printf("Restored %s into database %s\n", zipPath, newDB)
+		}
+		record := dbRecord{Name: newDB, Action: "restored", File: zipPath}
+		record.Neutralized = neutralizeAfter(cmd, newDB)
+		if isJSONOutput(cmd) {
+			printJSON(cmd, record)
+		}
+	},
+}
+
+var dbNeutralizeCmd = &cobra.Command{
+	Use:   "neutralize <dbname>",
+	Short: "Neutralizes a database.",
+	Long:  "Disables outgoing mail servers, crons, payment providers, webhooks... by running the neutralization scripts of the installed modules from the checked-out repositories, like odoo-bin neutralize. The checked-out version must match the database's.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		dbname := args[0]
+		neutralize(cmd, dbname)
+		if isJSONOutput(cmd) {
+			printJSON(cmd, dbRecord{Name: dbname, Action: "neutralized", Neutralized: true})
 		}
 	},
 }
 
+func neutralize(cmd *cobra.Command, dbname string) {
+	err := lib.NeutralizeDB(cmd.Context(), dbname)
+	if err != nil {
+		cmd.PrintErrf("Failed to neutralize database %s: %v\n", dbname, err)
+		os.Exit(1)
+	}
+	if !isJSONOutput(cmd) {
+		cmd.Printf("Neutralized database %s\n", dbname)
+	}
+}
+
+// neutralizeAfter neutralizes the database when the --neutralize flag is set.
+func neutralizeAfter(cmd *cobra.Command, dbname string) bool {
+	if neutralizeFlag, _ := cmd.Flags().GetBool("neutralize"); !neutralizeFlag {
+		return false
+	}
+	neutralize(cmd, dbname)
+	return true
+}
+
 func init() {
 	dbDropCmd.Flags().BoolP("all", "a", false, "Drop all databases")
 	dbCmd.AddCommand(dbDropCmd)
 
+	dbCmd.AddCommand(dbCreateCmd)
+	dbCmd.AddCommand(dbLinkCmd)
+
+	dbDuplicateCmd.Flags().BoolP("neutralize", "n", false, "Neutralize the new database.")
 	dbCmd.AddCommand(dbDuplicateCmd)
+	dbListCmd.Flags().BoolP("long", "l", false, "Show version, modules, size and last modification.")
 	dbCmd.AddCommand(dbListCmd)
+	dbCmd.AddCommand(dbInfoCmd)
+	dbCmd.AddCommand(dbBackupCmd)
+	dbRestoreCmd.Flags().BoolP("neutralize", "n", false, "Neutralize the restored database.")
+	dbCmd.AddCommand(dbRestoreCmd)
+	dbCmd.AddCommand(dbNeutralizeCmd)
 
 	rootCmd.AddCommand(dbCmd)
 }
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+	"github.com/spf13/cobra"
+	"github.com/ziriraha/odv/lib"
+	"github.com/ziriraha/odv/views"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Shows the changes of all repositories as one diff.",
+	Long:  "Will show the unstaged changes of all repositories, the staged ones with --staged, or the changes of the current branches since their version branch with --base, as one diff with paths prefixed by the repository name.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		staged, _ := cmd.Flags().GetBool("staged")
+		sinceBase, _ := cmd.Flags().GetBool("base")
+		stat, _ := cmd.Flags().GetBool("stat")
+		noPager, _ := cmd.Flags().GetBool("no-pager")
+
+		diffs := make([]string, len(lib.GetSortedRepoNames()))
+		errs := make([]error, len(lib.GetSortedRepoNames()))
+		var wg sync.WaitGroup
+		for i, repoName := range lib.GetSortedRepoNames() {
+			wg.Go(func() {
+				repository := lib.GetRepository(repoName)
+				opts := lib.DiffOptions{
+					Staged: staged,
+					Stat:   stat,
+					Color:  lipgloss.ColorProfile() != termenv.Ascii,
+					Prefix: repoName,
+				}
+				if sinceBase {
+					branch := repository.GetCurrentBranch(ctx)
+					refs, ok := taskBaseRefs(ctx, repoName, repository, branch)
+					if !ok {
+						return
+					}
+					if len(refs) == 0 {
+						errs[i] = fmt.Errorf("repo '%s': base branch of '%s' not found", repoName, branch)
+						return
+					}
+					var err error
+					if opts.Base, err = repository.MergeBase(ctx, refs...); err != nil {
+						errs[i] = fmt.Errorf("repo '%s': %w", repoName, err)
+						return
+					}
+				}
+				diffs[i], errs[i] = repository.GetDiff(ctx, opts)
+				if errs[i] != nil {
+					errs[i] = fmt.Errorf("repo '%s': %w", repoName, errs[i])
+				}
+			})
+		}
+		wg.Wait()
+
+		for _, err := range errs {
+			if err != nil {
+				cmd.PrintErrf("Failed to diff: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		var b strings.Builder
+		for i, repoName := range lib.GetSortedRepoNames() {
+			if diffs[i] == "" {
+				continue
+			}
+			// The paths of --stat have no prefix, give each summary a header instead.
+			if stat {
+				fmt.Fprintln(&b, views.RenderRepoName(repoName))
+			}
+			b.WriteString(diffs[i])
+		}
+		if b.Len() == 0 {
+			return
+		}
+
+		if noPager {
+			cmd.Print(b.String())
+			return
+		}
+		if err := views.Page(cmd.OutOrStdout(), b.String()); err != nil {
+			cmd.PrintErrf("Failed to run the pager: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	diffCmd.Flags().Bool("staged", false, "Show the staged changes.")
+	diffCmd.Flags().Bool("base", false, "Show the changes of the current branches since their version branch.")
+	diffCmd.Flags().Bool("stat", false, "Show a summary of the changed files per repository.")
+	diffCmd.Flags().Bool("no-pager", false, "Do not pipe the diff into $PAGER.")
+	diffCmd.MarkFlagsMutuallyExclusive("staged", "base")
+	rootCmd.AddCommand(diffCmd)
+}
@@ -11,6 +11,28 @@ import (
 	"github.com/ziriraha/odv/views"
 )
 
+type branchPresenceOutput struct {
+	Branch       string          `json:"branch"`
+	Repositories map[string]bool `json:"repositories"`
+}
+
+// renderPresence renders the letter of each repository (but .workspace)
+// having the branch, presence being indexed like the sorted repositories.
+func renderPresence(presence []bool) string {
+	var indicator strings.Builder
+	for repoIndex, repoName := range lib.GetSortedRepoNames() {
+		if repoName == ".workspace" {
+			continue
+		}
+		if presence[repoIndex] {
+			indicator.WriteString(views.RenderRepoLetter(repoName))
+		} else {
+			indicator.WriteString(views.FaintStyle.Render("·"))
+		}
+	}
+	return indicator.String()
+}
+
 var listCmd = &cobra.Command{
 	Use:     "list",
 	Aliases: []string{"ls"},
@@ -20,7 +42,7 @@ var listCmd = &cobra.Command{
 		showVersions, _ := cmd.Flags().GetBool("all")
 		branchPresence := make(map[string][]bool)
 
-		for _, branch := range lib.GetAllBranches() {
+		for _, branch := range lib.GetAllBranches(cmd.Context()) {
 			if !lib.IsVersionBranch(branch) || showVersions {
 				branchPresence[branch] = make([]bool, len(lib.GetSortedRepoNames()))
 			}
@@ -33,7 +55,7 @@ var listCmd = &cobra.Command{
 			}
 			repository := lib.GetRepository(repoName)
 			wg.Go(func() {
-				for _, branch := range repository.GetBranches() {
+				for _, branch := range repository.GetBranches(cmd.Context()) {
 					if _, ok := branchPresence[branch]; ok {
 						branchPresence[branch][repoIndex] = true
 					}
@@ -44,20 +66,22 @@ var listCmd = &cobra.Command{
 
 		branches := slices.Collect(maps.Keys(branchPresence))
 		lib.SortBranches(branches)
-		for _, branch := range branches {
-			var indicator strings.Builder
-
-			for repoIndex, repoName := range lib.GetSortedRepoNames() {
-				if repoName == ".workspace" {
-					continue
-				}
-				if branchPresence[branch][repoIndex] {
-					indicator.WriteString(views.RenderRepoLetter(repoName))
-				} else {
-					indicator.WriteString(views.FaintStyle.Render("·"))
+		if isJSONOutput(cmd) {
+			output := make([]branchPresenceOutput, 0, len(branches))
+			for _, branch := range branches {
+				presence := branchPresenceOutput{Branch: branch, Repositories: make(map[string]bool)}
+				for repoIndex, repoName := range lib.GetSortedRepoNames() {
+					if repoName != ".workspace" {
+						presence.Repositories[repoName] = branchPresence[branch][repoIndex]
+					}
 				}
+				output = append(output, presence)
 			}
-			cmd.Printf("%s - %s\n", indicator.String(), branch)
+			printJSON(cmd, output)
+			return
+		}
+		for _, branch := range branches {
+			cmd.Printf("%s - %s\n", renderPresence(branchPresence[branch]), branch)
 		}
 	},
 }
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+	"github.com/ziriraha/odv/lib"
+	"github.com/ziriraha/odv/views"
+)
+
+type logEntry struct {
+	Repository string `json:"repository"`
+	lib.Commit
+}
+
+// taskBaseRefs returns the refs of the branch the current branch of the
+// repository started from, both the local and the origin one as either may be
+// behind, or false when it is on that branch.
+func taskBaseRefs(ctx context.Context, repoName string, repo *lib.Repository, branch string) ([]string, bool) {
+	base := lib.DetectVersion(branch)
+	if repoName == ".workspace" {
+		base = "main"
+	}
+	if branch == base {
+		return nil, false
+	}
+	var refs []string
+	for _, ref := range []string{base, lib.RemoteOrigin + "/" + base} {
+		if repo.RefExists(ctx, ref) {
+			refs = append(refs, ref)
+		}
+	}
+	return refs, true
+}
+
+func renderLogEntry(entry logEntry) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s %s %s %s\n",
+		views.GetRepoStyle(entry.Repository).Render(entry.Hash[:min(10, len(entry.Hash))]),
+		views.RenderRepoName(entry.Repository),
+		views.FaintStyle.Render(formatTime(entry.Date)),
+		entry.Subject,
+		views.FaintStyle.Render("("+entry.Author+")"))
+	if entry.Stat != "" {
+		for line := range strings.SplitSeq(entry.Stat, "\n") {
+			fmt.Fprintf(&b, "   |%s\n", line)
+		}
+	}
+	return b.String()
+}
+
+var logCmd = &cobra.Command{
+	Use:   "log",
+	Short: "Shows the commits of the current task in all repositories.",
+	Long:  "Will show the commits between the version branch and HEAD in all repositories (main for .workspace), interleaved by date.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		stat, _ := cmd.Flags().GetBool("stat")
+		sinceBase, _ := cmd.Flags().GetBool("since-base")
+		limit, _ := cmd.Flags().GetInt("max-count")
+		if !sinceBase && limit == 0 {
+			limit = 20
+		}
+
+		logs := make([][]logEntry, len(lib.GetSortedRepoNames()))
+		errs := make([]error, len(lib.GetSortedRepoNames()))
+		var wg sync.WaitGroup
+		for i, repoName := range lib.GetSortedRepoNames() {
+			wg.Go(func() {
+				repository := lib.GetRepository(repoName)
+				revs := []string{"HEAD"}
+				if sinceBase {
+					refs, ok := taskBaseRefs(ctx, repoName, repository, repository.GetCurrentBranch(ctx))
+					if !ok {
+						return
+					}
+					for _, ref := range refs {
+						revs = append(revs, "^"+ref)
+					}
+				}
+				commits, err := repository.GetLog(ctx, revs, limit, stat)
+				if err != nil {
+					errs[i] = fmt.Errorf("repo '%s': %w", repoName, err)
+					return
+				}
+				for _, commit := range commits {
+					logs[i] = append(logs[i], logEntry{Repository: repoName, Commit: commit})
+				}
+			})
+		}
+		wg.Wait()
+
+		for _, err := range errs {
+			if err != nil {
+				cmd.PrintErrf("Failed to read the log: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		entries := slices.Concat(logs...)
+		slices.SortStableFunc(entries, func(a, b logEntry) int { return b.Date.Compare(a.Date) })
+		if limit > 0 && len(entries) > limit {
+			entries = entries[:limit]
+		}
+		if entries == nil {
+			entries = []logEntry{}
+		}
+
+		if isJSONOutput(cmd) {
+			printJSON(cmd, entries)
+			return
+		}
+		if len(entries) == 0 {
+			cmd.Println("No commits since the version branches.")
+			return
+		}
+		for _, entry := range entries {
+			cmd.Print(renderLogEntry(entry))
+		}
+	},
+}
+
+func init() {
+	logCmd.Flags().Bool("stat", false, "Show the files changed by each commit.")
+	logCmd.Flags().Bool("since-base", true, "Only show the commits since the version branch, --since-base=false shows the whole history.")
+	logCmd.Flags().IntP("max-count", "n", 0, "Limit the number of commits shown (20 by default without --since-base).")
+	rootCmd.AddCommand(logCmd)
+}
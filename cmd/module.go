@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+	"github.com/ziriraha/odv/lib"
+	"github.com/ziriraha/odv/views"
+)
+
+// changedModules returns the modules with files changed on the current branch
+// of each repository since its version branch, uncommitted changes included.
+// Repositories without addons are left out, those whose version branch is
+// missing are warned about and left out too.
+func changedModules(cmd *cobra.Command) ([]string, error) {
+	ctx := cmd.Context()
+	modules := make([][]string, len(lib.GetSortedRepoNames()))
+	warnings := make([]string, len(lib.GetSortedRepoNames()))
+	errs := make([]error, len(lib.GetSortedRepoNames()))
+	var wg sync.WaitGroup
+	for i, repoName := range lib.GetSortedRepoNames() {
+		if !lib.HasAddons(repoName) {
+			continue
+		}
+		wg.Go(func() {
+			repository := lib.GetRepository(repoName)
+			branch := repository.GetCurrentBranch(ctx)
+			refs, ok := taskBaseRefs(ctx, repoName, repository, branch)
+			if !ok {
+				return
+			}
+			if len(refs) == 0 {
+				warnings[i] = fmt.Sprintf("repo '%s': base branch of '%s' not found, skipping it", repoName, branch)
+				return
+			}
+			base, err := repository.MergeBase(ctx, refs...)
+			if err != nil {
+				errs[i] = fmt.Errorf("repo '%s': %w", repoName, err)
+				return
+			}
+			files, err := repository.GetChangedFiles(ctx, base)
+			if err != nil {
+				errs[i] = fmt.Errorf("repo '%s': %w", repoName, err)
+				return
+			}
+			modules[i] = lib.ModulesOfPaths(repoName, files)
+		})
+	}
+	wg.Wait()
+	for _, warning := range warnings {
+		if warning != "" {
+			cmd.PrintErrln(views.WarningStyle.Render(warning))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return slices.Compact(slices.Sorted(slices.Values(slices.Concat(modules...)))), nil
+}
+
+// runModules runs odoo-bin on the database with the option (-i or -u) for the
+// modules, stopping once they are loaded.
+func runModules(cmd *cobra.Command, dbName, option string, modules, extraArgs []string) {
+	args := append([]string{option, strings.Join(modules, ","), "--stop-after-init"}, extraArgs...)
+	odooCmd, err := lib.OdooCommand(dbName, args...)
+	if err != nil {
+		cmd.PrintErrln("Failed to prepare odoo-bin:", err)
+		os.Exit(1)
+	}
+	odooCmd.Stdin = os.Stdin
+	odooCmd.Stdout = os.Stdout
+	odooCmd.Stderr = os.Stderr
+	exitOnOdooError(cmd, runOdoo(cmd, odooCmd, nil))
+}
+
+// splitModuleArgs splits the arguments into the database, the modules and the
+// arguments after '--' that are forwarded to odoo-bin.
+func splitModuleArgs(cmd *cobra.Command, args []string) (string, []string, []string) {
+	var extraArgs []string
+	if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+		args, extraArgs = args[:dash], args[dash:]
+	}
+	if len(args) == 0 {
+		cmd.PrintErrln("No database given.")
+		os.Exit(1)
+	}
+	return args[0], slices.Clip(args[1:]), extraArgs
+}
+
+var moduleCmd = &cobra.Command{
+	Use:   "module",
+	Short: "Installs and upgrades modules.",
+}
+
+var moduleInstallCmd = &cobra.Command{
+	Use:   "install <dbname> <module>... [-- odoo-bin args...]",
+	Short: "Installs modules on a database.",
+	Long:  "Will run odoo-bin with the configured repositories to install the modules on the database, stopping once they are installed. Arguments after '--' are forwarded to odoo-bin.",
+	Args:  cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		dbName, modules, extraArgs := splitModuleArgs(cmd, args)
+		if len(modules) == 0 {
+			cmd.PrintErrln("No modules to install.")
+			os.Exit(1)
+		}
+		cmd.PrintErrf("Installing %s on %s\n", strings.Join(modules, ", "), dbName)
+		runModules(cmd, dbName, "-i", modules, extraArgs)
+	},
+}
+
+var moduleUpgradeCmd = &cobra.Command{
+	Use:   "upgrade <dbname> [module...] [-- odoo-bin args...]",
+	Short: "Upgrades modules of a database.",
+	Long:  "Will run odoo-bin with the configured repositories to upgrade the modules of the database, stopping once they are upgraded. With --changed, the modules with files changed on the current branches since their version branch are upgraded too. Arguments after '--' are forwarded to odoo-bin.",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		dbName, modules, extraArgs := splitModuleArgs(cmd, args)
+		if changed, _ := cmd.Flags().GetBool("changed"); changed {
+			changedMods, err := changedModules(cmd)
+			if err != nil {
+				cmd.PrintErrf("Failed to find the changed modules: %v\n", err)
+				os.Exit(1)
+			}
+			for _, module := range changedMods {
+				if !slices.Contains(modules, module) {
+					modules = append(modules, module)
+				}
+			}
+		}
+		if len(modules) == 0 {
+			cmd.PrintErrln("No modules to upgrade.")
+			os.Exit(1)
+		}
+		cmd.PrintErrf("Upgrading %s on %s\n", strings.Join(modules, ", "), dbName)
+		runModules(cmd, dbName, "-u", modules, extraArgs)
+	},
+}
+
+func init() {
+	moduleUpgradeCmd.Flags().Bool("changed", false, "Upgrade the modules changed on the current branches.")
+	moduleCmd.AddCommand(moduleInstallCmd)
+	moduleCmd.AddCommand(moduleUpgradeCmd)
+	rootCmd.AddCommand(moduleCmd)
+}
@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spf13/cobra"
+	"github.com/ziriraha/odv/lib"
+	"github.com/ziriraha/odv/views"
+)
+
+type newRepoExtra struct {
+	create   bool // create the branch from base, otherwise just switch to base
+	base     string
+	worktree *worktreePlan
+}
+
+func performNew(ctx context.Context, repoIndex int, repo *lib.Repository, branch string, extra *newRepoExtra) tea.Cmd {
+	return func() tea.Msg {
+		startTime := time.Now()
+		var err error
+		switch {
+		case extra.worktree != nil:
+			err = extra.worktree.apply(ctx, repo, branch)
+		case extra.create:
+			useMainCheckout(repo)
+			err = repo.CreateBranchFrom(ctx, extra.base, branch)
+		default:
+			useMainCheckout(repo)
+			err = repo.SwitchBranch(ctx, extra.base)
+		}
+		return views.RepoOperationDoneMsg{
+			RepoIndex: repoIndex,
+			Err:       err,
+			Duration:  time.Since(startTime),
+		}
+	}
+}
+
+var newCmd = &cobra.Command{
+	Use:   "new <branch>",
+	Short: "Create a new dev branch.",
+	Long:  "Will create the branch from its version branch in the selected repositories (community by default), switch the other repositories to the version branch and create the .workspace branch from main.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		branch, _ := strings.CutPrefix(args[0], "odoo-dev:")
+		version := lib.DetectVersion(branch)
+		if lib.IsVersionBranch(branch) {
+			cmd.PrintErrf("'%s' is a version branch, dev branches are named '<version>-<description>'\n", branch)
+			os.Exit(1)
+		}
+
+		selected := make(map[string]bool)
+		for repoName := range lib.GetRepositories() {
+			if flag := cmd.Flags().Lookup(repoName); flag != nil && flag.Changed {
+				selected[repoName], _ = cmd.Flags().GetBool(repoName)
+			}
+		}
+		if len(selected) == 0 {
+			selected["community"] = true
+		}
+
+		states := make([]*views.RepoOperationState, len(lib.GetSortedRepoNames()))
+		extras := make([]*newRepoExtra, len(lib.GetSortedRepoNames()))
+		for i, repoName := range lib.GetSortedRepoNames() {
+			repository := lib.GetRepository(repoName)
+			extra := &newRepoExtra{base: version}
+			switch {
+			case repoName == ".workspace":
+				// .workspace follows every branch, reuse it if it was left behind.
+				extra.create, extra.base = true, "main"
+				if repository.BranchExists(ctx, branch) {
+					extra.create, extra.base = false, branch
+				}
+			case selected[repoName]:
+				if repository.BranchExists(ctx, branch) {
+					cmd.PrintErrf("branch '%s' already exists in repo '%s'\n", branch, repoName)
+					os.Exit(1)
+				}
+				if !repository.BranchExists(ctx, version) {
+					cmd.PrintErrf("version branch '%s' was not found in repo '%s'\n", version, repoName)
+					os.Exit(1)
+				}
+				extra.create = true
+				if lib.GetConfig().Worktrees {
+					extra.worktree = &worktreePlan{create: true, base: version}
+				}
+			default:
+				if !repository.BranchExists(ctx, version) {
+					extra.base = lib.FallbackBranch
+				}
+				worktree, err := planWorktree(ctx, repoName, repository, extra.base)
+				if err != nil {
+					cmd.PrintErrf("failed to list the worktrees of repo '%s': %v\n", repoName, err)
+					os.Exit(1)
+				}
+				extra.worktree = worktree
+			}
+			s := views.NewRepoOperationState(repoName)
+			states[i] = &s
+			extras[i] = extra
+		}
+
+		failCount, err := views.RepoBranchSpinnerView{
+			Title:  fmt.Sprintf("Creating '%s'", branch),
+			States: states,
+			LaunchOp: func(ctx context.Context, i int) tea.Cmd {
+				return performNew(ctx, i, lib.GetRepository(states[i].Name), branch, extras[i])
+			},
+			RenderRepo: func(i int, state *views.RepoOperationState) string {
+				extra := extras[i]
+				switch state.Status {
+				case views.StatusInProgress:
+					if extra.create {
+						return state.RenderInProgress(fmt.Sprintf("creating from '%s'", extra.base))
+					}
+					return state.RenderInProgress(fmt.Sprintf("switching to '%s'", extra.base))
+				case views.StatusDone:
+					if extra.worktree != nil {
+						target := extra.base
+						if extra.create {
+							target = branch
+						}
+						return state.RenderDone(extra.worktree.describe(target))
+					}
+					if extra.create {
+						return state.RenderDone(fmt.Sprintf("created from '%s'", extra.base))
+					}
+					return state.RenderDone(fmt.Sprintf("switched to '%s'", extra.base))
+				case views.StatusFailed:
+					if extra.create {
+						return state.RenderFailed(fmt.Sprintf("failed to create from '%s'", extra.base))
+					}
+					return state.RenderFailed(fmt.Sprintf("failed to switch to '%s'", extra.base))
+				}
+				return ""
+			},
+		}.Run(ctx)
+
+		if err != nil {
+			cmd.PrintErrln(err)
+			os.Exit(1)
+		}
+		if failCount > 0 {
+			os.Exit(1)
+		}
+		saveActiveWorktrees(cmd, branch)
+	},
+}
+
+func init() {
+	for repoName := range lib.GetConfig().Repositories {
+		if repoName != ".workspace" {
+			newCmd.Flags().Bool(repoName, false, fmt.Sprintf("Create the branch in %s.", repoName))
+		}
+	}
+	rootCmd.AddCommand(newCmd)
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"time"
@@ -14,14 +15,20 @@ import (
 type pullRepoExtra struct {
 	branch     string
 	skipReason string
+	autostash  bool
+	stash      *autostash
 }
 
-func performPull(repoIndex int, repo *lib.Repository, extra *pullRepoExtra) tea.Cmd {
+func performPull(ctx context.Context, repoIndex int, repo *lib.Repository, extra *pullRepoExtra) tea.Cmd {
 	return func() tea.Msg {
 		startTime := time.Now()
+		var err error
+		extra.stash, err = withAutostash(ctx, extra.autostash, repo, "pull", func() error {
+			return repo.PullRebase(ctx, lib.GetRemoteForBranch(extra.branch), extra.branch)
+		})
 		return views.RepoOperationDoneMsg{
 			RepoIndex: repoIndex,
-			Err:       repo.PullRebase(lib.GetRemoteForBranch(extra.branch), extra.branch),
+			Err:       err,
 			Duration:  time.Since(startTime),
 		}
 	}
@@ -36,16 +43,17 @@ var pullCmd = &cobra.Command{
 		var extras []*pullRepoExtra
 		var repoNames []string
 		skipped := make(map[int]bool)
+		useAutostash, _ := cmd.Flags().GetBool("autostash")
 
 		for _, repoName := range lib.GetSortedRepoNames() {
 			if repoName == ".workspace" {
 				continue
 			}
 			repository := lib.GetRepository(repoName)
-			curBranch := repository.GetCurrentBranch()
+			curBranch := repository.GetCurrentBranch(cmd.Context())
 			s := views.NewRepoOperationState(repoName)
 
-			extra := &pullRepoExtra{branch: curBranch}
+			extra := &pullRepoExtra{branch: curBranch, autostash: useAutostash}
 			idx := len(states)
 
 			if !lib.IsVersionBranch(curBranch) {
@@ -67,8 +75,8 @@ var pullCmd = &cobra.Command{
 			Title:          "Pulling branches",
 			States:         states,
 			SkippedIndices: skipped,
-			LaunchOp: func(i int) tea.Cmd {
-				return performPull(i, lib.GetRepository(states[i].Name), extras[i])
+			LaunchOp: func(ctx context.Context, i int) tea.Cmd {
+				return performPull(ctx, i, lib.GetRepository(states[i].Name), extras[i])
 			},
 			RenderRepo: func(i int, state *views.RepoOperationState) string {
 				extra := extras[i]
@@ -82,13 +90,14 @@ var pullCmd = &cobra.Command{
 				case views.StatusInProgress:
 					return state.RenderInProgress(fmt.Sprintf("pulling '%s'", extra.branch))
 				case views.StatusDone:
-					return state.RenderDone(fmt.Sprintf("pulled '%s'", extra.branch))
+					return state.RenderDone(fmt.Sprintf("pulled '%s'", extra.branch)) + extra.stash.render()
 				case views.StatusFailed:
-					return state.RenderFailed(fmt.Sprintf("failed to pull '%s'", extra.branch))
+					return state.RenderFailed(fmt.Sprintf("failed to pull '%s'", extra.branch)) + extra.stash.render()
 				}
 				return ""
 			},
-		}.Run()
+			RenderNote: func(i int) string { return extras[i].stash.render() },
+		}.Run(cmd.Context())
 
 		if err != nil {
 			cmd.PrintErrln(err)
@@ -101,5 +110,6 @@ var pullCmd = &cobra.Command{
 }
 
 func init() {
+	addAutostashFlag(pullCmd)
 	rootCmd.AddCommand(pullCmd)
 }
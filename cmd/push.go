@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spf13/cobra"
+	"github.com/ziriraha/odv/lib"
+	"github.com/ziriraha/odv/views"
+)
+
+type pushRepoExtra struct {
+	branch     string
+	skipReason string
+	result     lib.PushResult
+}
+
+func performPush(ctx context.Context, repoIndex int, repo *lib.Repository, extra *pushRepoExtra, forceWithLease bool) tea.Cmd {
+	return func() tea.Msg {
+		startTime := time.Now()
+		result, err := repo.Push(ctx, lib.RemoteDev, extra.branch, forceWithLease)
+		extra.result = result
+		return views.RepoOperationDoneMsg{
+			RepoIndex: repoIndex,
+			Err:       err,
+			Duration:  time.Since(startTime),
+		}
+	}
+}
+
+func describePushResult(result lib.PushResult) string {
+	switch result {
+	case lib.PushCreated:
+		return "created"
+	case lib.PushUpdated:
+		return "updated"
+	case lib.PushForced:
+		return "force-updated"
+	}
+	return "already up to date"
+}
+
+var pushCmd = &cobra.Command{
+	Use:   "push",
+	Short: "Pushes current dev branch.",
+	Long:  "Will push the current branch to the dev remote in every odoo repository that is on a dev branch and has a dev remote. Version branches are never pushed.",
+	Run: func(cmd *cobra.Command, args []string) {
+		forceWithLease, _ := cmd.Flags().GetBool("force-with-lease")
+		var states []*views.RepoOperationState
+		var extras []*pushRepoExtra
+		skipped := make(map[int]bool)
+
+		for _, repoName := range lib.GetSortedRepoNames() {
+			if repoName == ".workspace" {
+				continue
+			}
+			repository := lib.GetRepository(repoName)
+			curBranch := repository.GetCurrentBranch(cmd.Context())
+			s := views.NewRepoOperationState(repoName)
+
+			extra := &pushRepoExtra{branch: curBranch}
+			idx := len(states)
+
+			if lib.IsVersionBranch(curBranch) {
+				extra.skipReason = fmt.Sprintf("on version branch '%s'", curBranch)
+				skipped[idx] = true
+			} else if !repository.HasRemote(cmd.Context(), lib.RemoteDev) {
+				// e.g. upgrade, which only tracks origin.
+				extra.skipReason = fmt.Sprintf("no '%s' remote", lib.RemoteDev)
+				skipped[idx] = true
+			}
+
+			states = append(states, &s)
+			extras = append(extras, extra)
+		}
+
+		if len(states)-len(skipped) == 0 {
+			cmd.Println("No repositories on dev branches to push.")
+			return
+		}
+
+		failCount, err := views.RepoBranchSpinnerView{
+			Title:          "Pushing branches",
+			States:         states,
+			SkippedIndices: skipped,
+			LaunchOp: func(ctx context.Context, i int) tea.Cmd {
+				return performPush(ctx, i, lib.GetRepository(states[i].Name), extras[i], forceWithLease)
+			},
+			RenderRepo: func(i int, state *views.RepoOperationState) string {
+				extra := extras[i]
+				if skipped[i] {
+					return fmt.Sprintf("%s %s - skipped (%s)\n",
+						views.FaintStyle.Render("⊘"),
+						views.RenderRepoName(state.Name),
+						views.FaintStyle.Render(extra.skipReason))
+				}
+				remoteBranch := fmt.Sprintf("%s/%s", lib.RemoteDev, extra.branch)
+				switch state.Status {
+				case views.StatusInProgress:
+					return state.RenderInProgress(fmt.Sprintf("pushing '%s'", extra.branch))
+				case views.StatusDone:
+					return state.RenderDone(fmt.Sprintf("%s '%s'", describePushResult(extra.result), remoteBranch))
+				case views.StatusFailed:
+					if extra.result == lib.PushRejected {
+						return state.RenderFailed(fmt.Sprintf("rejected by '%s'", remoteBranch))
+					}
+					return state.RenderFailed(fmt.Sprintf("failed to push '%s'", extra.branch))
+				}
+				return ""
+			},
+		}.Run(cmd.Context())
+
+		if err != nil {
+			cmd.PrintErrln(err)
+			os.Exit(1)
+		}
+		if failCount > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	pushCmd.Flags().Bool("force-with-lease", false, "Force the push unless the remote branch changed since it was last fetched (e.g. after a rebase).")
+	rootCmd.AddCommand(pushCmd)
+}
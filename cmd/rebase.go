@@ -1,8 +1,12 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -15,29 +19,59 @@ import (
 type rebaseRepoExtra struct {
 	branch     string
 	skipReason string
-	conflicts  []string
+	conflicts  []lib.FileChange
+	autostash  bool
+	stash      *autostash
 }
 
-func isConflictIndicator(status string) bool {
-	switch status {
-	case "UU", "AA", "DD", "AU", "UA", "DU", "UD":
-		return true
+type rebaseAction struct {
+	flag    string
+	run     func(repo *lib.Repository, ctx context.Context) error
+	doing   string
+	done    string
+	failure string
+}
+
+var rebaseActions = []rebaseAction{
+	{"continue", (*lib.Repository).RebaseContinue, "continuing rebase", "rebase continued", "failed to continue rebase"},
+	{"skip", (*lib.Repository).RebaseSkip, "skipping commit", "commit skipped", "failed to skip commit"},
+	{"abort", (*lib.Repository).RebaseAbort, "aborting rebase", "rebase aborted", "failed to abort rebase"},
+}
+
+func collectConflicts(ctx context.Context, repo *lib.Repository, extra *rebaseRepoExtra) {
+	extra.conflicts, _ = repo.GetConflicts(ctx)
+}
+
+func renderConflicts(state *views.RepoOperationState, message string, conflicts []lib.FileChange) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s - %s\n",
+		views.Cross,
+		views.RenderRepoName(state.Name),
+		message)
+	for _, change := range conflicts {
+		indicator := views.ColorizeStatusIndicator(change.Index + change.Worktree)
+		fmt.Fprintf(&b, "   |%s %s\n", indicator, change.Path)
 	}
-	return false
+	return b.String()
+}
+
+func renderSkipped(state *views.RepoOperationState, reason string) string {
+	return fmt.Sprintf("%s %s - skipped (%s)\n",
+		views.FaintStyle.Render("⊘"),
+		views.RenderRepoName(state.Name),
+		views.FaintStyle.Render(reason))
 }
 
-func performRebase(repoIndex int, repo *lib.Repository, extra *rebaseRepoExtra) tea.Cmd {
+func performRebase(ctx context.Context, repoIndex int, repo *lib.Repository, extra *rebaseRepoExtra) tea.Cmd {
 	return func() tea.Msg {
 		startTime := time.Now()
-		err := repo.PullRebase(lib.RemoteOrigin, extra.branch)
+		var err error
+		extra.stash, err = withAutostash(ctx, extra.autostash, repo, "rebase", func() error {
+			return repo.PullRebase(ctx, lib.RemoteOrigin, extra.branch)
+		})
 
 		if err != nil {
-			changes, _ := repo.GetStatus()
-			for _, change := range changes {
-				if len(change) >= 2 && isConflictIndicator(change[0:2]) {
-					extra.conflicts = append(extra.conflicts, change)
-				}
-			}
+			collectConflicts(ctx, repo, extra)
 		}
 
 		return views.RepoOperationDoneMsg{
@@ -48,26 +82,224 @@ func performRebase(repoIndex int, repo *lib.Repository, extra *rebaseRepoExtra)
 	}
 }
 
+func performRebaseAction(ctx context.Context, repoIndex int, repo *lib.Repository, extra *rebaseRepoExtra, action rebaseAction) tea.Cmd {
+	return func() tea.Msg {
+		startTime := time.Now()
+		err := action.run(repo, ctx)
+
+		// Continuing or skipping can stop again on the conflicts of the next commit.
+		if err != nil || repo.IsRebasing(ctx) {
+			collectConflicts(ctx, repo, extra)
+		}
+
+		return views.RepoOperationDoneMsg{
+			RepoIndex: repoIndex,
+			Err:       err,
+			Duration:  time.Since(startTime),
+		}
+	}
+}
+
+func editorCommand(path string) *exec.Cmd {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	args := strings.Fields(editor)
+	if len(args) == 0 {
+		args = []string{"vi"}
+	}
+	return exec.Command(args[0], append(args[1:], path)...)
+}
+
+func listConflicts(ctx context.Context) ([]views.Conflict, error) {
+	var conflicts []views.Conflict
+	for _, repoName := range lib.GetSortedRepoNames() {
+		if repoName == ".workspace" {
+			continue
+		}
+		repository := lib.GetRepository(repoName)
+		changes, err := repository.GetConflicts(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("repo '%s': %w", repoName, err)
+		}
+		// Keep the repositories whose files are all resolved, their rebase has yet to be continued.
+		if len(changes) == 0 && repository.IsRebasing(ctx) {
+			conflicts = append(conflicts, views.Conflict{Repo: repoName})
+		}
+		for _, change := range changes {
+			conflicts = append(conflicts, views.Conflict{
+				Repo:   repoName,
+				Status: change.Index + change.Worktree,
+				Path:   change.Path,
+			})
+		}
+	}
+	return conflicts, nil
+}
+
+// resolveConflicts opens the conflict resolver on the conflicted files of
+// every repository.
+func resolveConflicts(cmd *cobra.Command) {
+	ctx := cmd.Context()
+	err := views.ConflictResolverView{
+		Title:         "Resolve rebase conflicts",
+		ListConflicts: func() ([]views.Conflict, error) { return listConflicts(ctx) },
+		EditCommand: func(c views.Conflict) *exec.Cmd {
+			return editorCommand(filepath.Join(lib.GetRepository(c.Repo).Path(), c.Path))
+		},
+		Diff: func(c views.Conflict) (string, error) {
+			return lib.GetRepository(c.Repo).GetConflictDiff(ctx, c.Path)
+		},
+		MarkResolved: func(c views.Conflict) error {
+			return lib.GetRepository(c.Repo).MarkResolved(ctx, c.Path)
+		},
+		TakeSide: func(c views.Conflict, theirs bool) error {
+			return lib.GetRepository(c.Repo).TakeConflictSide(ctx, c.Path, theirs)
+		},
+		ContinueRebase: func(repo string) error {
+			return lib.GetRepository(repo).RebaseContinue(ctx)
+		},
+	}.Run()
+
+	if errors.Is(err, views.ErrNotInteractive) {
+		cmd.PrintErrln("Resolving conflicts needs a terminal, resolve them with git and run 'odv rebase --continue'.")
+		os.Exit(1)
+	} else if err != nil {
+		cmd.PrintErrln(err)
+		os.Exit(1)
+	}
+}
+
+// offerResolve opens the conflict resolver when a rebase stopped on conflicts
+// and there is a terminal to run it in.
+func offerResolve(cmd *cobra.Command, extras []*rebaseRepoExtra) {
+	if !views.IsInteractive() {
+		return
+	}
+	for _, extra := range extras {
+		if len(extra.conflicts) > 0 {
+			resolveConflicts(cmd)
+			return
+		}
+	}
+}
+
+// runRebaseAction continues, skips or aborts the rebase in the repositories
+// where one is stopped.
+func runRebaseAction(cmd *cobra.Command, action rebaseAction) {
+	var states []*views.RepoOperationState
+	var extras []*rebaseRepoExtra
+	skipped := make(map[int]bool)
+
+	for _, repoName := range lib.GetSortedRepoNames() {
+		if repoName == ".workspace" {
+			continue
+		}
+		repository := lib.GetRepository(repoName)
+		s := views.NewRepoOperationState(repoName)
+		extra := &rebaseRepoExtra{}
+		idx := len(states)
+
+		if !repository.IsRebasing(cmd.Context()) {
+			extra.skipReason = "no rebase in progress"
+			skipped[idx] = true
+		}
+
+		states = append(states, &s)
+		extras = append(extras, extra)
+	}
+
+	if len(states)-len(skipped) == 0 {
+		cmd.Println("No rebase in progress.")
+		return
+	}
+
+	failCount, err := views.RepoBranchSpinnerView{
+		Title:          fmt.Sprintf("Rebase --%s", action.flag),
+		States:         states,
+		SkippedIndices: skipped,
+		LaunchOp: func(ctx context.Context, i int) tea.Cmd {
+			return performRebaseAction(ctx, i, lib.GetRepository(states[i].Name), extras[i], action)
+		},
+		RenderRepo: func(i int, state *views.RepoOperationState) string {
+			extra := extras[i]
+			if skipped[i] {
+				return renderSkipped(state, extra.skipReason)
+			}
+			switch state.Status {
+			case views.StatusInProgress:
+				return state.RenderInProgress(action.doing)
+			case views.StatusDone:
+				if len(extra.conflicts) > 0 {
+					return renderConflicts(state, "stopped on new conflicts", extra.conflicts)
+				}
+				return state.RenderDone(action.done)
+			case views.StatusFailed:
+				if len(extra.conflicts) > 0 {
+					return renderConflicts(state, "unresolved conflicts", extra.conflicts)
+				}
+				return state.RenderFailed(action.failure)
+			}
+			return ""
+		},
+	}.Run(cmd.Context())
+
+	if err != nil {
+		cmd.PrintErrln(err)
+		os.Exit(1)
+	}
+	if action.flag != "abort" {
+		offerResolve(cmd, extras)
+	}
+	exitOnRebaseFailure(failCount, extras)
+}
+
+// exitOnRebaseFailure exits with an error status when a repository failed or
+// stopped on conflicts.
+func exitOnRebaseFailure(failCount int, extras []*rebaseRepoExtra) {
+	if failCount > 0 {
+		os.Exit(1)
+	}
+	for _, extra := range extras {
+		if len(extra.conflicts) > 0 {
+			os.Exit(1)
+		}
+	}
+}
+
 var rebaseCmd = &cobra.Command{
 	Use:   "rebase",
 	Short: "Rebase current branch on its version branch.",
-	Long:  "Will run git pull --rebase origin <versionBranch> on all repositories. Conflicts are left for the user to resolve.",
+	Long:  "Will run git pull --rebase origin <versionBranch> on all repositories. Conflicts are left for the user to resolve, then --continue, --skip or --abort act on the repositories where the rebase stopped.",
 	Run: func(cmd *cobra.Command, args []string) {
+		if resolve, _ := cmd.Flags().GetBool("resolve"); resolve {
+			resolveConflicts(cmd)
+			return
+		}
+		for _, action := range rebaseActions {
+			if set, _ := cmd.Flags().GetBool(action.flag); set {
+				runRebaseAction(cmd, action)
+				return
+			}
+		}
+
 		var states []*views.RepoOperationState
 		var extras []*rebaseRepoExtra
 		var repoNames []string
 		skipped := make(map[int]bool)
+		useAutostash, _ := cmd.Flags().GetBool("autostash")
 
 		for _, repoName := range lib.GetSortedRepoNames() {
 			if repoName == ".workspace" {
 				continue
 			}
 			repository := lib.GetRepository(repoName)
-			curBranch := repository.GetCurrentBranch()
+			curBranch := repository.GetCurrentBranch(cmd.Context())
 			s := views.NewRepoOperationState(repoName)
 
 			version := lib.DetectVersion(curBranch)
-			extra := &rebaseRepoExtra{branch: version}
+			extra := &rebaseRepoExtra{branch: version, autostash: useAutostash}
 			idx := len(states)
 
 			if curBranch == version {
@@ -85,53 +317,49 @@ var rebaseCmd = &cobra.Command{
 			return
 		}
 
-		_, err := views.RepoBranchSpinnerView{
+		failCount, err := views.RepoBranchSpinnerView{
 			Title:          "Rebasing branches",
 			States:         states,
 			SkippedIndices: skipped,
-			LaunchOp: func(i int) tea.Cmd {
-				return performRebase(i, lib.GetRepository(repoNames[i]), extras[i])
+			LaunchOp: func(ctx context.Context, i int) tea.Cmd {
+				return performRebase(ctx, i, lib.GetRepository(repoNames[i]), extras[i])
 			},
 			RenderRepo: func(i int, state *views.RepoOperationState) string {
 				extra := extras[i]
 				if skipped[i] {
-					return fmt.Sprintf("%s %s - skipped (%s)\n",
-						views.FaintStyle.Render("⊘"),
-						views.RenderRepoName(state.Name),
-						views.FaintStyle.Render(extra.skipReason))
+					return renderSkipped(state, extra.skipReason)
 				}
 				switch state.Status {
 				case views.StatusInProgress:
 					return state.RenderInProgress(fmt.Sprintf("rebasing on '%s'", extra.branch))
 				case views.StatusDone:
-					return state.RenderDone(fmt.Sprintf("rebased on '%s'", extra.branch))
+					return state.RenderDone(fmt.Sprintf("rebased on '%s'", extra.branch)) + extra.stash.render()
 				case views.StatusFailed:
-					hasConflicts := len(extra.conflicts) > 0
-					if hasConflicts {
-						var b strings.Builder
-						fmt.Fprintf(&b, "%s %s - conflicts rebasing on '%s'\n",
-							views.Cross,
-							views.RenderRepoName(state.Name),
-							extra.branch)
-						for _, change := range extra.conflicts {
-							indicator := views.ColorizeStatusIndicator(change[0:2])
-							fmt.Fprintf(&b, "   |%s %s\n", indicator, change[3:])
-						}
-						return b.String()
+					if len(extra.conflicts) > 0 {
+						return renderConflicts(state, fmt.Sprintf("conflicts rebasing on '%s'", extra.branch), extra.conflicts) + extra.stash.render()
 					}
-					return state.RenderFailed(fmt.Sprintf("failed to rebase on '%s'", extra.branch))
+					return state.RenderFailed(fmt.Sprintf("failed to rebase on '%s'", extra.branch)) + extra.stash.render()
 				}
 				return ""
 			},
-		}.Run()
+			RenderNote: func(i int) string { return extras[i].stash.render() },
+		}.Run(cmd.Context())
 
 		if err != nil {
 			cmd.PrintErrln(err)
 			os.Exit(1)
 		}
+		offerResolve(cmd, extras)
+		exitOnRebaseFailure(failCount, extras)
 	},
 }
 
 func init() {
+	rebaseCmd.Flags().Bool("continue", false, "Continue the stopped rebases once conflicts are resolved.")
+	rebaseCmd.Flags().Bool("skip", false, "Skip the current commit of the stopped rebases.")
+	rebaseCmd.Flags().Bool("abort", false, "Abort the stopped rebases.")
+	rebaseCmd.Flags().Bool("resolve", false, "Resolve the conflicts of the stopped rebases interactively.")
+	rebaseCmd.MarkFlagsMutuallyExclusive("continue", "skip", "abort", "resolve")
+	addAutostashFlag(rebaseCmd)
 	rootCmd.AddCommand(rebaseCmd)
 }
@@ -1,15 +1,66 @@
 package cmd
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
 	"github.com/spf13/cobra"
 	"github.com/ziriraha/odv/views"
 )
 
+const (
+	outputText  = "text"
+	outputPlain = "plain"
+	outputJSON  = "json"
+)
+
 var rootCmd = &cobra.Command{
 	Use:   "odv",
 	Short: "An all in one tool for Odoo development.",
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		switch getOutputFormat(cmd) {
+		case outputText:
+		case outputPlain, outputJSON:
+			lipgloss.SetColorProfile(termenv.Ascii)
+		default:
+			return fmt.Errorf("invalid output format '%s' (expected %s, %s or %s)", getOutputFormat(cmd), outputText, outputPlain, outputJSON)
+		}
+		views.NoTUI, _ = cmd.Flags().GetBool("no-tui")
+		return nil
+	},
+}
+
+func getOutputFormat(cmd *cobra.Command) string {
+	format, _ := cmd.Flags().GetString("output")
+	return format
+}
+
+func isJSONOutput(cmd *cobra.Command) bool {
+	return getOutputFormat(cmd) == outputJSON
+}
+
+func printJSON(cmd *cobra.Command, v any) {
+	encoder := json.NewEncoder(cmd.OutOrStdout())
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		cmd.PrintErrln("Failed to encode output:", err)
+		os.Exit(1)
+	}
+}
+
+// printResult prints the record in JSON output mode, or the text otherwise.
+func printResult(cmd *cobra.Command, record any, format string, a ...any) {
+	if isJSONOutput(cmd) {
+		printJSON(cmd, record)
+	} else {
+		cmd.Printf(format, a...)
+	}
 }
 
 func Execute() {
@@ -17,7 +68,16 @@ func Execute() {
 	rootCmd.SetErr(os.Stderr)
 	rootCmd.SetErrPrefix(views.ErrorStyle.Render("ERROR "))
 
-	if rootCmd.Execute() != nil {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	// Once cancelled, a second ctrl+c terminates what does not watch the context.
+	context.AfterFunc(ctx, stop)
+	if rootCmd.ExecuteContext(ctx) != nil {
 		os.Exit(1)
 	}
 }
+
+func init() {
+	rootCmd.PersistentFlags().StringP("output", "o", outputText, "Output format: text, plain (no colors) or json.")
+	rootCmd.PersistentFlags().Bool("no-tui", false, "Print line based progress instead of interactive views.")
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"os/signal"
+
+	"github.com/spf13/cobra"
+	"github.com/ziriraha/odv/lib"
+)
+
+// runOdoo runs odoo-bin until it exits, registered as the instance when
+// given, and returns how it exited. odoo-bin receives ctrl+c itself, odv only
+// has to wait for it to shut down.
+func runOdoo(cmd *cobra.Command, odooCmd *exec.Cmd, instance *lib.Instance) error {
+	// Not signal.Ignore: the child would inherit it and not see ctrl+c either.
+	signal.Notify(make(chan os.Signal, 1), os.Interrupt)
+	if err := odooCmd.Start(); err != nil {
+		cmd.PrintErrln("Failed to run odoo-bin:", err)
+		os.Exit(1)
+	}
+	if instance != nil {
+		if err := instance.Register(odooCmd.Process.Pid); err != nil {
+			cmd.PrintErrln("Failed to register the instance:", err)
+		}
+		defer instance.Unregister()
+	}
+	return odooCmd.Wait()
+}
+
+// exitOnOdooError exits with the status of odoo-bin when it failed.
+func exitOnOdooError(cmd *cobra.Command, err error) {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		os.Exit(max(exitErr.ExitCode(), 1))
+	} else if err != nil {
+		cmd.PrintErrln("Failed to run odoo-bin:", err)
+		os.Exit(1)
+	}
+}
+
+var runCmd = &cobra.Command{
+	Use:   "run [-- odoo-bin args...]",
+	Short: "Runs odoo-bin with the configured repositories.",
+	Long:  "Will start community's odoo-bin with an addons path built from the configured repositories. The database defaults to the one linked to the current branch, or the db prefix followed by the branch name, which then gets linked. Arguments after '--' are forwarded to odoo-bin. With --name, a separate instance is run on ports allocated from the configured range, so that several servers can run side by side. With --detach, odoo-bin runs in the background with its output in a log file, and is managed with stop and restart.",
+	Run: func(cmd *cobra.Command, args []string) {
+		name, _ := cmd.Flags().GetString("name")
+		branch := lib.GetCurrentBranch(cmd.Context())
+		dbName, _ := cmd.Flags().GetString("database")
+		if dbName == "" {
+			dbName = lib.GetBranchDB(branch)
+			if _, linked := lib.GetLinkedDB(branch); !linked {
+				if err := lib.LinkDB(branch, dbName); err != nil {
+					cmd.PrintErrln("Failed to link database to branch:", err)
+				}
+			}
+		}
+
+		instance, err := lib.NewInstance(name, branch, dbName, args)
+		if err != nil {
+			cmd.PrintErrln("Failed to prepare the instance:", err)
+			os.Exit(1)
+		}
+		if detach, _ := cmd.Flags().GetBool("detach"); detach {
+			startDetached(cmd, instance)
+			return
+		}
+
+		odooCmd, err := instance.Command()
+		if err != nil {
+			cmd.PrintErrln("Failed to prepare odoo-bin:", err)
+			os.Exit(1)
+		}
+		odooCmd.Stdin = os.Stdin
+		odooCmd.Stdout = os.Stdout
+		odooCmd.Stderr = os.Stderr
+
+		exitOnOdooError(cmd, runOdoo(cmd, odooCmd, instance))
+	},
+}
+
+func init() {
+	runCmd.Flags().StringP("database", "d", "", "Database to use (defaults to the database linked to the current branch).")
+	addInstanceFlag(runCmd)
+	runCmd.Flags().Bool("detach", false, "Run odoo-bin in the background.")
+	rootCmd.AddCommand(runCmd)
+}
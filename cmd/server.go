@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/ziriraha/odv/lib"
+)
+
+func startDetached(cmd *cobra.Command, instance *lib.Instance) {
+	if err := instance.StartDetached(); err != nil {
+		cmd.PrintErrln("Failed to start odoo-bin:", err)
+		os.Exit(1)
+	}
+	cmd.Printf("Odoo '%s' started on port %d with database %s (pid %d), logging to %s\n",
+		instance.Name, instance.Port, instance.Database, instance.PID, instance.LogPath)
+}
+
+func getInstance(cmd *cobra.Command, name string) *lib.Instance {
+	instance, err := lib.GetInstance(name)
+	if err != nil {
+		cmd.PrintErrln("Failed to read the instances:", err)
+		os.Exit(1)
+	}
+	return instance
+}
+
+func addInstanceFlag(cmd *cobra.Command) {
+	cmd.Flags().String("name", lib.DefaultInstance, "Name of the instance.")
+}
+
+var stopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Stops an odoo instance.",
+	Long:  fmt.Sprintf("Will stop the odoo instance run by odv with the name (the default one without --name), or the process listening on port %d when the default instance is not run by odv. Odoo gets the stop timeout to shut down before being killed.", lib.GetConfig().OdooPort),
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		name, _ := cmd.Flags().GetString("name")
+		instance := getInstance(cmd, name)
+		var err error
+		switch {
+		case instance != nil:
+			err = instance.Stop()
+		case name == lib.DefaultInstance:
+			err = findKillOdooProcess(lib.GetConfig().OdooPort)
+		default:
+			err = fmt.Errorf("no instance named '%s' is running", name)
+		}
+		if err != nil {
+			cmd.PrintErrln("Failed to stop odoo:", err)
+			os.Exit(1)
+		}
+		cmd.Printf("Odoo '%s' stopped.\n", name)
+	},
+}
+
+var restartCmd = &cobra.Command{
+	Use:   "restart",
+	Short: "Restarts an odoo instance running in the background.",
+	Long:  "Will stop the odoo instance started by 'odv run --detach' with the name (the default one without --name) and start it again in the background with the same ports, database and arguments, on the checkouts that are now active.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		name, _ := cmd.Flags().GetString("name")
+		instance := getInstance(cmd, name)
+		if instance == nil || !instance.Detached {
+			cmd.PrintErrf("No instance named '%s' is running in the background, start one with 'odv run --detach'.\n", name)
+			os.Exit(1)
+		}
+		if err := instance.Stop(); err != nil {
+			cmd.PrintErrln("Failed to stop odoo:", err)
+			os.Exit(1)
+		}
+		instance.Branch = lib.GetCurrentBranch(cmd.Context())
+		startDetached(cmd, instance)
+	},
+}
+
+var psCmd = &cobra.Command{
+	Use:   "ps",
+	Short: "Lists the running odoo instances.",
+	Long:  "Will list the odoo instances run by odv with their branch, database, ports, pid and uptime.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		instances, err := lib.GetInstances()
+		if err != nil {
+			cmd.PrintErrln("Failed to read the instances:", err)
+			os.Exit(1)
+		}
+		if isJSONOutput(cmd) {
+			if instances == nil {
+				instances = []*lib.Instance{}
+			}
+			printJSON(cmd, instances)
+			return
+		}
+		if len(instances) == 0 {
+			cmd.Println("No odoo instances running.")
+			return
+		}
+
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tBRANCH\tDATABASE\tPORT\tPID\tUPTIME")
+		for _, instance := range instances {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d/%d\t%d\t%s\n", instance.Name, instance.Branch, instance.Database,
+				instance.Port, instance.GeventPort, instance.PID, time.Since(instance.Started).Round(time.Second))
+		}
+		w.Flush()
+	},
+}
+
+func init() {
+	addInstanceFlag(stopCmd)
+	addInstanceFlag(restartCmd)
+	rootCmd.AddCommand(stopCmd)
+	rootCmd.AddCommand(restartCmd)
+	rootCmd.AddCommand(psCmd)
+}
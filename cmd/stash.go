@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/ziriraha/odv/lib"
+	"github.com/ziriraha/odv/views"
+)
+
+type wipSwitch struct {
+	from       string
+	to         string
+	stashed    bool
+	restored   *lib.WIPStash
+	restoreErr error
+}
+
+// withBranchStash stashes the work in progress of the branch being left, runs
+// switchOp and restores the work in progress of the branch switched to.
+func withBranchStash(ctx context.Context, repo *lib.Repository, from, to string, switchOp func() error) (*wipSwitch, error) {
+	commit, err := repo.StashWIP(ctx, from)
+	if err != nil {
+		return nil, fmt.Errorf("stashing the work in progress failed: %w", err)
+	}
+	wip := &wipSwitch{from: from, to: to}
+	if err := switchOp(); err != nil {
+		if commit != "" {
+			err = errors.Join(err, repo.StashPop(ctx, commit))
+		}
+		return wip, err
+	}
+	wip.stashed = commit != ""
+	wip.restored, wip.restoreErr = repo.RestoreWIP(ctx, to)
+	return wip, nil
+}
+
+func (w *wipSwitch) render() string {
+	if w == nil {
+		return ""
+	}
+	var b strings.Builder
+	if w.stashed {
+		fmt.Fprintf(&b, "   %s\n", views.FaintStyle.Render(fmt.Sprintf("work in progress of '%s' stashed", w.from)))
+	}
+	switch {
+	case w.restoreErr != nil && w.restored != nil:
+		fmt.Fprintf(&b, "   %s\n", views.WarningStyle.Render(fmt.Sprintf(
+			"restoring the work in progress of '%s' conflicted, resolve it then run 'git stash drop %s'", w.to, w.restored.Ref)))
+	case w.restoreErr != nil:
+		fmt.Fprintf(&b, "   %s\n", views.ErrorStyle.Render(fmt.Sprintf("failed to restore the work in progress: %v", w.restoreErr)))
+	case w.restored != nil:
+		fmt.Fprintf(&b, "   %s\n", views.FaintStyle.Render(fmt.Sprintf("work in progress of '%s' restored", w.to)))
+	}
+	return b.String()
+}
+
+type stashRecord struct {
+	Repository string `json:"repository"`
+	lib.WIPStash
+}
+
+// getWIPStashes lists the work in progress stashes of every repository,
+// optionally only the ones of a branch.
+func getWIPStashes(ctx context.Context, branch string) ([]stashRecord, error) {
+	records := []stashRecord{}
+	for _, repoName := range lib.GetSortedRepoNames() {
+		if repoName == ".workspace" {
+			continue
+		}
+		stashes, err := lib.GetRepository(repoName).GetWIPStashes(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("repo '%s': %w", repoName, err)
+		}
+		for _, stash := range stashes {
+			if branch == "" || stash.Branch == branch {
+				records = append(records, stashRecord{Repository: repoName, WIPStash: stash})
+			}
+		}
+	}
+	return records, nil
+}
+
+var stashCmd = &cobra.Command{
+	Use:   "stash",
+	Short: "Manage the work in progress stashed when switching branches.",
+}
+
+var stashListCmd = &cobra.Command{
+	Use:   "list [branch]",
+	Short: "Lists the work in progress stashes.",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var branch string
+		if len(args) > 0 {
+			branch = args[0]
+		}
+		records, err := getWIPStashes(cmd.Context(), branch)
+		if err != nil {
+			cmd.PrintErrf("Failed to list stashes: %v\n", err)
+			os.Exit(1)
+		}
+
+		if isJSONOutput(cmd) {
+			printJSON(cmd, records)
+			return
+		}
+		if len(records) == 0 {
+			cmd.Println("No work in progress stashed.")
+			return
+		}
+		slices.SortStableFunc(records, func(a, b stashRecord) int { return strings.Compare(a.Branch, b.Branch) })
+		for i, record := range records {
+			if i == 0 || records[i-1].Branch != record.Branch {
+				cmd.Println(views.BoldStyle.Render(record.Branch))
+			}
+			cmd.Println(views.RepoLine(record.Repository, "%s %s", record.Ref, views.FaintStyle.Render(formatTime(record.Created))))
+		}
+	},
+}
+
+var stashDropCmd = &cobra.Command{
+	Use:   "drop [branch]",
+	Short: "Drops the work in progress stashes of a branch.",
+	Long:  "Drops the work in progress stashed for the branch (the current one by default) in every repository.",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		branch := lib.GetCurrentBranch(ctx)
+		if len(args) > 0 {
+			branch = args[0]
+		}
+		records, err := getWIPStashes(ctx, branch)
+		if err != nil {
+			cmd.PrintErrf("Failed to list stashes: %v\n", err)
+			os.Exit(1)
+		}
+		if len(records) == 0 {
+			cmd.Printf("No work in progress stashed for '%s'.\n", branch)
+			return
+		}
+		if !confirm(cmd, fmt.Sprintf("Drop %d stash(es) of '%s'?", len(records), branch)) {
+			return
+		}
+
+		failed := false
+		for _, record := range records {
+			if err := lib.GetRepository(record.Repository).StashDrop(ctx, record.Commit); err != nil {
+				cmd.PrintErrf("Failed to drop %s in %s: %v\n", record.Ref, record.Repository, err)
+				failed = true
+				continue
+			}
+			cmd.Println(views.RepoLine(record.Repository, "dropped %s", record.Ref))
+		}
+		if failed {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	stashDropCmd.Flags().BoolP("yes", "y", false, "Do not ask for confirmation.")
+	stashCmd.AddCommand(stashListCmd)
+	stashCmd.AddCommand(stashDropCmd)
+	rootCmd.AddCommand(stashCmd)
+}
@@ -2,6 +2,8 @@ package cmd
 
 import (
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
 	"sync"
 
@@ -10,20 +12,60 @@ import (
 	"github.com/ziriraha/odv/views"
 )
 
+type repoStatus struct {
+	Repository string           `json:"repository"`
+	Branch     string           `json:"branch"`
+	Remote     string           `json:"remote"`
+	Ahead      *int             `json:"ahead"`  // nil when the branch has no remote counterpart
+	Behind     *int             `json:"behind"` // nil when the branch has no remote counterpart
+	Changes    []lib.FileChange `json:"changes"`
+	Stashes    []lib.WIPStash   `json:"-"`
+}
+
+type statusOutput struct {
+	Branch       string         `json:"branch"`
+	Database     *string        `json:"database"`
+	Stashes      map[string]int `json:"stashes"` // work in progress stashes per branch
+	Repositories []repoStatus   `json:"repositories"`
+}
+
+func renderRepoStatus(status repoStatus, short bool) string {
+	output := strings.Builder{}
+	branch := status.Branch
+	if status.Ahead == nil && status.Behind == nil {
+		branch = views.LocalBranchStyle.Render(branch)
+	}
+	fmt.Fprint(&output, views.RepoLine(status.Repository, "%s ", branch))
+	if status.Ahead != nil && *status.Ahead > 0 {
+		output.WriteString(views.AheadStyle.Render(fmt.Sprintf("↑%d", *status.Ahead)))
+	}
+	if status.Behind != nil && *status.Behind > 0 {
+		output.WriteString(views.BehindStyle.Render(fmt.Sprintf("↓%d", *status.Behind)))
+	}
+	output.WriteString("\n")
+	if !short {
+		for _, change := range status.Changes {
+			indicator := views.ColorizeStatusIndicator(change.Index + change.Worktree)
+			path := change.Path
+			if change.OrigPath != "" {
+				path = change.OrigPath + " -> " + change.Path
+			}
+			fmt.Fprintf(&output, "   |%s %s\n", indicator, path)
+		}
+	}
+	return output.String()
+}
+
 var statusCmd = &cobra.Command{
 	Use:     "status",
 	Aliases: []string{"st"},
 	Short:   "Prints current branch's status.",
 	Long:    "Will print the current branch in all three odoo repositories.",
 	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
 		short, _ := cmd.Flags().GetBool("short")
 
-		type repoStatus struct {
-			name   string
-			status string
-		}
-
-		statuses := make([]repoStatus, len(lib.GetSortedRepoNames()))
+		statuses := make([]*repoStatus, len(lib.GetSortedRepoNames()))
 		var wg sync.WaitGroup
 
 		for i, repoName := range lib.GetSortedRepoNames() {
@@ -33,46 +75,63 @@ var statusCmd = &cobra.Command{
 			wg.Go(func() {
 				repository := lib.GetRepository(repoName)
 				var repoWg sync.WaitGroup
-				var ahead, behind int
-				var changes []string
+				status := &repoStatus{Repository: repoName, Changes: []lib.FileChange{}}
 
-				curBranch := repository.GetCurrentBranch()
+				status.Branch = repository.GetCurrentBranch(ctx)
+				status.Remote = lib.RemoteOrigin
+				if repoName != "upgrade" {
+					status.Remote = lib.GetRemoteForBranch(status.Branch)
+				}
 				repoWg.Go(func() {
-					remote := lib.RemoteOrigin
-					if repoName != "upgrade" {
-						remote = lib.GetRemoteForBranch(curBranch)
+					ahead, behind, err := repository.GetAheadBehindInfo(ctx, status.Remote, status.Branch)
+					if err == nil {
+						status.Ahead, status.Behind = &ahead, &behind
 					}
-					ahead, behind, _ = repository.GetAheadBehindInfo(remote, curBranch)
 				})
-				repoWg.Go(func() { changes, _ = repository.GetStatus() })
-				repoWg.Wait()
-
-				output := strings.Builder{}
-				if ahead < 0 && behind < 0 {
-					curBranch = views.LocalBranchStyle.Render(curBranch)
-				}
-				fmt.Fprint(&output, views.RepoLine(repoName, "%s ", curBranch))
-				if ahead > 0 {
-					output.WriteString(views.AheadStyle.Render(fmt.Sprintf("↑%d", ahead)))
-				}
-				if behind > 0 {
-					output.WriteString(views.BehindStyle.Render(fmt.Sprintf("↓%d", behind)))
-				}
-				output.WriteString("\n")
-				if !short {
+				repoWg.Go(func() {
+					status.Stashes, _ = repository.GetWIPStashes(ctx)
+				})
+				repoWg.Go(func() {
+					changes, _ := repository.GetStatus(ctx)
 					for _, change := range changes {
-						indicator := views.ColorizeStatusIndicator(change[0:2])
-						change = fmt.Sprintf("%s %s", indicator, change[3:])
-						fmt.Fprintf(&output, "   |%s\n", change)
+						status.Changes = append(status.Changes, lib.ParseStatusLine(change))
 					}
-				}
-				statuses[i] = repoStatus{name: repoName, status: output.String()}
+				})
+				repoWg.Wait()
+				statuses[i] = status
 			})
 		}
 		wg.Wait()
 
+		output := statusOutput{Branch: lib.GetCurrentBranch(ctx), Stashes: map[string]int{}, Repositories: []repoStatus{}}
+		if dbName, ok := lib.GetLinkedDB(output.Branch); ok {
+			output.Database = &dbName
+		}
 		for _, status := range statuses {
-			cmd.Print(status.status)
+			if status != nil {
+				output.Repositories = append(output.Repositories, *status)
+				for _, stash := range status.Stashes {
+					output.Stashes[stash.Branch]++
+				}
+			}
+		}
+
+		if isJSONOutput(cmd) {
+			printJSON(cmd, output)
+			return
+		}
+		for _, status := range output.Repositories {
+			cmd.Print(renderRepoStatus(status, short))
+		}
+		if output.Database != nil {
+			cmd.Printf("%s %s\n", views.FaintStyle.Render("database:"), *output.Database)
+		}
+		if len(output.Stashes) > 0 {
+			var counts []string
+			for _, branch := range slices.Sorted(maps.Keys(output.Stashes)) {
+				counts = append(counts, fmt.Sprintf("%s (%d)", branch, output.Stashes[branch]))
+			}
+			cmd.Printf("%s %s\n", views.FaintStyle.Render("stashes:"), strings.Join(counts, ", "))
 		}
 	},
 }
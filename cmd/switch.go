@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"slices"
@@ -13,15 +15,24 @@ import (
 	"github.com/ziriraha/odv/views"
 )
 
-func performSwitch(repoIndex int, repo *lib.Repository, state *views.RepoOperationState, targetBranch string) tea.Cmd {
+type switchRepoExtra struct {
+	target      string
+	autostash   bool
+	branchStash bool
+	worktree    *worktreePlan
+	stash       *autostash
+	wip         *wipSwitch
+}
+
+func performSwitch(ctx context.Context, repoIndex int, repo *lib.Repository, state *views.RepoOperationState, fromBranch, toBranch string, extra *switchRepoExtra) tea.Cmd {
 	return func() tea.Msg {
 		startTime := time.Now()
 
 		if state.Name == ".workspace" {
-			changes, err := repo.GetStatus()
+			changes, err := repo.GetStatus(ctx)
 			if err == nil && len(changes) > 0 {
-				commitMessage := fmt.Sprintf("odv auto-commit %v\n\nBefore switching to '%s'", time.Now().Format(time.RFC3339), targetBranch)
-				if err := repo.CommitAll(commitMessage); err != nil {
+				commitMessage := fmt.Sprintf("odv auto-commit %v\n\nBefore switching to '%s'", time.Now().Format(time.RFC3339), extra.target)
+				if err := repo.CommitAll(ctx, commitMessage); err != nil {
 					return views.RepoOperationDoneMsg{
 						RepoIndex: repoIndex,
 						Err:       fmt.Errorf("auto-commit failed before switch: %w", err),
@@ -31,34 +42,141 @@ func performSwitch(repoIndex int, repo *lib.Repository, state *views.RepoOperati
 			}
 		}
 
+		if extra.worktree == nil {
+			useMainCheckout(repo)
+		}
+		// .workspace changes were committed above, there is nothing to stash.
+		var err error
+		switch {
+		case extra.worktree != nil:
+			err = extra.worktree.apply(ctx, repo, extra.target)
+		case state.Name == ".workspace":
+			err = repo.SwitchBranch(ctx, extra.target)
+		case extra.branchStash && fromBranch != toBranch:
+			extra.wip, err = withBranchStash(ctx, repo, fromBranch, toBranch, func() error {
+				return repo.SwitchBranch(ctx, extra.target)
+			})
+		default:
+			extra.stash, err = withAutostash(ctx, extra.autostash, repo, "switch", func() error {
+				return repo.SwitchBranch(ctx, extra.target)
+			})
+		}
 		return views.RepoOperationDoneMsg{
 			RepoIndex: repoIndex,
-			Err:       repo.SwitchBranch(targetBranch),
+			Err:       err,
 			Duration:  time.Since(startTime),
 		}
 	}
 }
 
+type fetchRepoExtra struct {
+	found    bool
+	noRemote bool
+}
+
+// fetchDevBranch fetches the branch from the dev remote, as a local branch
+// tracking it, in the repositories that don't have it yet. Repositories where
+// the remote doesn't have it, or that have no such remote, fall back like any
+// other switch.
+func fetchDevBranch(cmd *cobra.Command, branch string) {
+	var states []*views.RepoOperationState
+	var extras []*fetchRepoExtra
+	skipped := make(map[int]bool)
+	for _, repoName := range lib.GetSortedRepoNames() {
+		if repoName == ".workspace" {
+			continue
+		}
+		repository := lib.GetRepository(repoName)
+		extra := &fetchRepoExtra{noRemote: !repository.HasRemote(cmd.Context(), lib.RemoteDev)}
+		if extra.noRemote || repository.BranchExists(cmd.Context(), branch) {
+			skipped[len(states)] = true
+		}
+		s := views.NewRepoOperationState(repoName)
+		states = append(states, &s)
+		extras = append(extras, extra)
+	}
+	if len(states)-len(skipped) == 0 {
+		return
+	}
+
+	failCount, err := views.RepoBranchSpinnerView{
+		Title:          fmt.Sprintf("Fetching '%s'", branch),
+		States:         states,
+		SkippedIndices: skipped,
+		LaunchOp: func(ctx context.Context, i int) tea.Cmd {
+			return func() tea.Msg {
+				startTime := time.Now()
+				found, err := lib.GetRepository(states[i].Name).FetchTrackingBranch(ctx, lib.RemoteDev, branch)
+				extras[i].found = found
+				return views.RepoOperationDoneMsg{RepoIndex: i, Err: err, Duration: time.Since(startTime)}
+			}
+		},
+		RenderRepo: func(i int, state *views.RepoOperationState) string {
+			if extras[i].noRemote {
+				return renderSkipped(state, fmt.Sprintf("not on '%s'", lib.RemoteDev))
+			}
+			if skipped[i] {
+				return renderSkipped(state, "already local")
+			}
+			switch state.Status {
+			case views.StatusInProgress:
+				return state.RenderInProgress(fmt.Sprintf("fetching from '%s'", lib.RemoteDev))
+			case views.StatusDone:
+				if !extras[i].found {
+					return renderSkipped(state, fmt.Sprintf("not on '%s'", lib.RemoteDev))
+				}
+				return state.RenderDone(fmt.Sprintf("tracking '%s/%s'", lib.RemoteDev, branch))
+			case views.StatusFailed:
+				return state.RenderFailed(fmt.Sprintf("failed to fetch from '%s'", lib.RemoteDev))
+			}
+			return ""
+		},
+	}.Run(cmd.Context())
+
+	if err != nil {
+		cmd.PrintErrln(err)
+		os.Exit(1)
+	}
+	if failCount > 0 {
+		os.Exit(1)
+	}
+}
+
 var switchCmd = &cobra.Command{
 	Use:   "switch [branch]",
 	Short: "Switch to an existing branch.",
-	Long:  "If a branch is specified, switch to it directly. If no branch is specified, displays a list to choose from.",
+	Long:  "If a branch is specified, switch to it directly. If no branch is specified, displays a list to choose from. With --remote, branches of the dev remote are included and fetched when needed.",
 	Args:  cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
 		var selectedBranch string
-		branches := lib.GetAllBranches()
+		branches := lib.GetAllBranches(ctx)
 		if len(branches) == 0 {
 			cmd.Println("No branches found.")
 			os.Exit(1)
 		}
 
+		includeRemote, _ := cmd.Flags().GetBool("remote")
+		choices := branches
+		if includeRemote {
+			// Branches only on the remote are listed with its prefix.
+			for _, branch := range lib.GetAllRemoteBranches(ctx, lib.RemoteDev) {
+				if !slices.Contains(branches, branch) {
+					choices = append(choices, lib.RemoteDev+"/"+branch)
+				}
+			}
+		}
+
 		if len(args) == 0 {
 			choice, err := views.BranchSelectListView{
 				Title:    "Select a branch to switch to",
-				Branches: branches,
+				Branches: choices,
 			}.Run()
 
-			if err != nil {
+			if errors.Is(err, views.ErrNotInteractive) {
+				cmd.PrintErrln("No branch given and the branch picker needs an interactive terminal, pass the branch name instead.")
+				os.Exit(1)
+			} else if err != nil {
 				cmd.PrintErrf("Error running program: %v\n", err)
 				os.Exit(1)
 			}
@@ -70,6 +188,12 @@ var switchCmd = &cobra.Command{
 			selectedBranch, _ = strings.CutPrefix(args[0], "odoo-dev:")
 		}
 
+		if includeRemote {
+			selectedBranch, _ = strings.CutPrefix(selectedBranch, lib.RemoteDev+"/")
+			fetchDevBranch(cmd, selectedBranch)
+			branches = lib.GetAllBranches(ctx)
+		}
+
 		if !slices.Contains(branches, selectedBranch) {
 			cmd.PrintErrf("branch '%s' was not found\n", selectedBranch)
 			os.Exit(1)
@@ -79,16 +203,16 @@ var switchCmd = &cobra.Command{
 		repoBranches := make(map[string]string)
 		for repoName, repository := range lib.GetRepositories() {
 			branchName := selectedBranch
-			if !repository.BranchExists(branchName) {
+			if !repository.BranchExists(ctx, branchName) {
 				if repoName == ".workspace" {
-					if err := repository.CreateBranchFrom("main", branchName); err != nil {
+					if err := repository.CreateBranchFrom(ctx, "main", branchName); err != nil {
 						cmd.PrintErrf("failed to create branch for .workspace: %v", err)
 					}
 				} else {
 					branchName = version
-					if !repository.BranchExists(branchName) {
+					if !repository.BranchExists(ctx, branchName) {
 						branchName = lib.FallbackBranch
-						if !repository.BranchExists(branchName) {
+						if !repository.BranchExists(ctx, branchName) {
 							cmd.PrintErrf("no suitable branch found for '%s' in repo '%s' (tried: %s, %s, %s)\n", selectedBranch, repoName, selectedBranch, version, lib.FallbackBranch)
 							os.Exit(1)
 						}
@@ -98,33 +222,51 @@ var switchCmd = &cobra.Command{
 			repoBranches[repoName] = branchName
 		}
 
+		useAutostash, _ := cmd.Flags().GetBool("autostash")
+		useBranchStash, _ := cmd.Flags().GetBool("branch-stash")
+		fromBranch := lib.GetCurrentBranch(ctx)
+
 		states := make([]*views.RepoOperationState, len(lib.GetSortedRepoNames()))
-		targetBranches := make([]string, len(lib.GetSortedRepoNames()))
+		extras := make([]*switchRepoExtra, len(lib.GetSortedRepoNames()))
 		for i, repoName := range lib.GetSortedRepoNames() {
+			worktree, err := planWorktree(ctx, repoName, lib.GetRepository(repoName), repoBranches[repoName])
+			if err != nil {
+				cmd.PrintErrf("failed to list the worktrees of repo '%s': %v\n", repoName, err)
+				os.Exit(1)
+			}
 			s := views.NewRepoOperationState(repoName)
 			states[i] = &s
-			targetBranches[i] = repoBranches[repoName]
+			extras[i] = &switchRepoExtra{
+				target:      repoBranches[repoName],
+				autostash:   useAutostash,
+				branchStash: useBranchStash,
+				worktree:    worktree,
+			}
 		}
 
 		failCount, err := views.RepoBranchSpinnerView{
 			Title:  "Switching branches",
 			States: states,
-			LaunchOp: func(i int) tea.Cmd {
-				return performSwitch(i, lib.GetRepository(states[i].Name), states[i], targetBranches[i])
+			LaunchOp: func(ctx context.Context, i int) tea.Cmd {
+				return performSwitch(ctx, i, lib.GetRepository(states[i].Name), states[i], fromBranch, selectedBranch, extras[i])
 			},
 			RenderRepo: func(i int, state *views.RepoOperationState) string {
-				tb := targetBranches[i]
+				extra := extras[i]
 				switch state.Status {
 				case views.StatusInProgress:
-					return state.RenderInProgress(fmt.Sprintf("switching to '%s'", tb))
+					return state.RenderInProgress(fmt.Sprintf("switching to '%s'", extra.target))
 				case views.StatusDone:
-					return state.RenderDone(fmt.Sprintf("switched to '%s'", tb))
+					if extra.worktree != nil {
+						return state.RenderDone(extra.worktree.describe(extra.target))
+					}
+					return state.RenderDone(fmt.Sprintf("switched to '%s'", extra.target)) + extra.stash.render() + extra.wip.render()
 				case views.StatusFailed:
-					return state.RenderFailed(fmt.Sprintf("failed to switch to '%s'", tb))
+					return state.RenderFailed(fmt.Sprintf("failed to switch to '%s'", extra.target)) + extra.stash.render() + extra.wip.render()
 				}
 				return ""
 			},
-		}.Run()
+			RenderNote: func(i int) string { return extras[i].stash.render() + extras[i].wip.render() },
+		}.Run(ctx)
 
 		if err != nil {
 			cmd.PrintErrln(err)
@@ -133,9 +275,13 @@ var switchCmd = &cobra.Command{
 		if failCount > 0 {
 			os.Exit(1)
 		}
+		saveActiveWorktrees(cmd, selectedBranch)
 	},
 }
 
 func init() {
+	switchCmd.Flags().BoolP("remote", "r", false, "Include the branches of the dev remote, fetching the one switched to.")
+	addAutostashFlag(switchCmd)
+	switchCmd.Flags().Bool("branch-stash", lib.GetConfig().BranchStash, "Stash the work in progress of the branch left and restore the one of the branch switched to.")
 	rootCmd.AddCommand(switchCmd)
 }
@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/ziriraha/odv/lib"
+	"github.com/ziriraha/odv/views"
+)
+
+// parseTestSpecs turns module[:Class.method] arguments into the modules to
+// install and the matching --test-tags.
+func parseTestSpecs(specs []string) ([]string, string) {
+	var modules, tags []string
+	for _, spec := range specs {
+		module, _, _ := strings.Cut(spec, ":")
+		if !slices.Contains(modules, module) {
+			modules = append(modules, module)
+		}
+		tags = append(tags, "/"+spec)
+	}
+	return modules, strings.Join(tags, ",")
+}
+
+func renderTestReport(report *lib.TestReport, logPath string) string {
+	var b strings.Builder
+	for _, failure := range report.Failures {
+		fmt.Fprintf(&b, "%s %s %s: %s\n", views.Cross, views.ErrorStyle.Render(failure.Kind), failure.Module, failure.Test)
+		for line := range strings.SplitSeq(failure.Traceback, "\n") {
+			if line != "" {
+				fmt.Fprintf(&b, "    %s\n", views.FaintStyle.Render(line))
+			}
+		}
+	}
+	switch {
+	case !report.HasSummary:
+		fmt.Fprintln(&b, views.ErrorStyle.Render("Odoo stopped before reporting the test results, see "+logPath))
+	case report.Total == 0:
+		fmt.Fprintln(&b, views.WarningStyle.Render("No tests were run, check the module and test names."))
+	case report.Passed():
+		fmt.Fprintf(&b, "%s %s\n", views.Checkmark, views.SuccessStyle.Render(fmt.Sprintf("All %d tests passed.", report.Total)))
+	default:
+		fmt.Fprintln(&b, views.ErrorStyle.Render(fmt.Sprintf("%d failed, %d error(s) of %d tests.", report.Failed, report.Errors, report.Total)))
+	}
+	return b.String()
+}
+
+func writeJUnitFile(path string, report *lib.TestReport) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := lib.WriteJUnit(file, report); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+var testCmd = &cobra.Command{
+	Use:   "test <module>[:Class.method]... [-- odoo-bin args...]",
+	Short: "Runs the tests of modules.",
+	Long:  "Will install the modules on a fresh database (the db prefix followed by 'test-' and the branch name, dropped afterwards unless --keep) or on the database given with --db, run their tests, or only the given classes and methods, and report the failures found in the Odoo log, also as JUnit XML with --junit. Arguments after '--' are forwarded to odoo-bin.",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		keep, _ := cmd.Flags().GetBool("keep")
+		verbose, _ := cmd.Flags().GetBool("verbose")
+		specs, extraArgs := args, []string(nil)
+		if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+			specs, extraArgs = args[:dash], args[dash:]
+		}
+		if len(specs) == 0 {
+			cmd.PrintErrln("No modules to test.")
+			os.Exit(1)
+		}
+		modules, tags := parseTestSpecs(specs)
+
+		branch := lib.GetCurrentBranch(ctx)
+		dbName, _ := cmd.Flags().GetString("db")
+		fresh := dbName == ""
+		odooArgs := []string{"-i", strings.Join(modules, ","), "--test-tags", tags, "--stop-after-init"}
+		if fresh {
+			dbName = lib.GetTestDBName(branch)
+			if err := lib.DropDB(ctx, dbName); err != nil {
+				cmd.PrintErrf("Failed to drop the previous test database %s: %v\n", dbName, err)
+				os.Exit(1)
+			}
+		} else {
+			// The modules already installed are updated so that their at_install tests run too.
+			odooArgs = append(odooArgs, "-u", strings.Join(modules, ","))
+		}
+
+		// Named after the database so that test runs on other databases can run side by side.
+		instance, err := lib.NewInstance("test-"+dbName, branch, dbName, append(odooArgs, extraArgs...))
+		if err != nil {
+			cmd.PrintErrln("Failed to prepare the instance:", err)
+			os.Exit(1)
+		}
+		odooCmd, err := instance.Command()
+		if err != nil {
+			cmd.PrintErrln("Failed to prepare odoo-bin:", err)
+			os.Exit(1)
+		}
+		if err := os.MkdirAll(lib.GetStateDir(), 0o755); err != nil {
+			cmd.PrintErrln("Failed to create the state directory:", err)
+			os.Exit(1)
+		}
+		logPath := filepath.Join(lib.GetStateDir(), "odoo-"+instance.Name+".log")
+		logFile, err := os.Create(logPath)
+		if err != nil {
+			cmd.PrintErrln("Failed to create the log file:", err)
+			os.Exit(1)
+		}
+		defer logFile.Close()
+		var output io.Writer = logFile
+		if verbose {
+			output = io.MultiWriter(logFile, cmd.OutOrStdout())
+		} else {
+			cmd.PrintErrf("Testing %s on %s, logging to %s\n", strings.Join(modules, ", "), dbName, logPath)
+		}
+		odooCmd.Stdout = output
+		odooCmd.Stderr = output
+
+		// The results so far are still reported when the tests are interrupted.
+		runErr := runOdoo(cmd, odooCmd, instance)
+
+		if fresh && !keep {
+			if err := lib.DropDB(ctx, dbName); err != nil {
+				cmd.PrintErrf("Failed to drop the test database %s: %v\n", dbName, err)
+			}
+		}
+
+		if _, err := logFile.Seek(0, io.SeekStart); err != nil {
+			cmd.PrintErrln("Failed to read the log:", err)
+			os.Exit(1)
+		}
+		report, err := lib.ParseTestLog(logFile)
+		if err != nil {
+			cmd.PrintErrln("Failed to parse the log:", err)
+			os.Exit(1)
+		}
+		if junitPath, _ := cmd.Flags().GetString("junit"); junitPath != "" {
+			if err := writeJUnitFile(junitPath, report); err != nil {
+				cmd.PrintErrln("Failed to write the JUnit report:", err)
+			}
+		}
+		if isJSONOutput(cmd) {
+			printJSON(cmd, report)
+		} else {
+			cmd.Print(renderTestReport(report, logPath))
+		}
+
+		exitOnOdooError(cmd, runErr)
+		if !report.Passed() || report.Total == 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	testCmd.Flags().String("db", "", "Run the tests on this database instead of a fresh one.")
+	testCmd.Flags().Bool("keep", false, "Keep the fresh test database.")
+	testCmd.Flags().String("junit", "", "Also write the results as JUnit XML to this file.")
+	testCmd.Flags().BoolP("verbose", "v", false, "Show the Odoo log while the tests run.")
+	rootCmd.AddCommand(testCmd)
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"time"
@@ -21,7 +22,7 @@ type branchFetchedMsg struct {
 	repoIndex int
 }
 
-func fetchNextBranch(repoIndex int, repo *lib.Repository, state *views.RepoOperationState, extra *updateRepoExtra) tea.Cmd {
+func fetchNextBranch(ctx context.Context, repoIndex int, repo *lib.Repository, state *views.RepoOperationState, extra *updateRepoExtra) tea.Cmd {
 	if extra.currentIndex >= len(extra.branches) {
 		return func() tea.Msg {
 			return views.RepoOperationDoneMsg{
@@ -36,9 +37,9 @@ func fetchNextBranch(repoIndex int, repo *lib.Repository, state *views.RepoOpera
 	return func() tea.Msg {
 		var err error
 		if branch == extra.currentBranch {
-			err = repo.PullRebase(lib.RemoteOrigin, branch)
+			err = repo.PullRebase(ctx, lib.RemoteOrigin, branch)
 		} else {
-			err = repo.FetchRefspec(lib.RemoteOrigin, branch)
+			err = repo.FetchRefspec(ctx, lib.RemoteOrigin, branch)
 		}
 
 		if err != nil {
@@ -71,7 +72,7 @@ var updateCmd = &cobra.Command{
 			}
 
 			var versionBranches []string
-			for _, branch := range repository.GetBranches() {
+			for _, branch := range repository.GetBranches(cmd.Context()) {
 				if lib.IsVersionBranch(branch) {
 					versionBranches = append(versionBranches, branch)
 				}
@@ -86,7 +87,7 @@ var updateCmd = &cobra.Command{
 			extras = append(extras, &updateRepoExtra{
 				branches:      versionBranches,
 				currentIndex:  0,
-				currentBranch: repository.GetCurrentBranch(),
+				currentBranch: repository.GetCurrentBranch(cmd.Context()),
 			})
 			repoNames = append(repoNames, repoName)
 		}
@@ -100,14 +101,14 @@ var updateCmd = &cobra.Command{
 			Title:          "Updating repositories",
 			States:         states,
 			SkippedIndices: skipped,
-			LaunchOp: func(i int) tea.Cmd {
-				return fetchNextBranch(i, lib.GetRepository(repoNames[i]), states[i], extras[i])
+			LaunchOp: func(ctx context.Context, i int) tea.Cmd {
+				return fetchNextBranch(ctx, i, lib.GetRepository(repoNames[i]), states[i], extras[i])
 			},
-			OnMsg: func(msg tea.Msg, allStates []*views.RepoOperationState) tea.Cmd {
+			OnMsg: func(ctx context.Context, msg tea.Msg, allStates []*views.RepoOperationState) tea.Cmd {
 				if m, ok := msg.(branchFetchedMsg); ok {
 					extra := extras[m.repoIndex]
 					extra.currentIndex++
-					return fetchNextBranch(m.repoIndex, lib.GetRepository(repoNames[m.repoIndex]), allStates[m.repoIndex], extra)
+					return fetchNextBranch(ctx, m.repoIndex, lib.GetRepository(repoNames[m.repoIndex]), allStates[m.repoIndex], extra)
 				}
 				return nil
 			},
@@ -132,7 +133,7 @@ var updateCmd = &cobra.Command{
 				}
 				return ""
 			},
-		}.Run()
+		}.Run(cmd.Context())
 
 		if err != nil {
 			cmd.PrintErrln(err)
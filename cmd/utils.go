@@ -1,12 +1,17 @@
 package cmd
 
 import (
+	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"os"
-	"os/exec"
-	"strconv"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
+	tea "github.com/charmbracelet/bubbletea"
 	"github.com/spf13/cobra"
 	"github.com/ziriraha/odv/lib"
 	"github.com/ziriraha/odv/views"
@@ -17,64 +22,384 @@ var utilsCmd = &cobra.Command{
 	Short: "Utilities for managing odoo.",
 }
 
-func findKillOdooProcess() error {
-	odooPort := lib.GetConfig().OdooPort
-	pid, err := exec.Command("lsof", "-ti", fmt.Sprintf(":%d", odooPort)).CombinedOutput()
-	if err != nil || len(pid) == 0 {
-		return fmt.Errorf("no process found listening on port %d", odooPort)
-	}
-	pidInt, err := strconv.Atoi(strings.TrimSpace(string(pid)))
+// findKillOdooProcess stops the processes listening on the port, which odv
+// may not have started itself.
+func findKillOdooProcess(odooPort int) error {
+	pids, err := lib.FindListeningPIDs(odooPort)
 	if err != nil {
-		return fmt.Errorf("invalid pid: %v", err)
+		return fmt.Errorf("could not look up port %d: %v", odooPort, err)
 	}
-	process, err := os.FindProcess(pidInt)
-	if err != nil {
-		return fmt.Errorf("could not find process: %v", err)
+	if len(pids) == 0 {
+		return fmt.Errorf("no process found listening on port %d", odooPort)
 	}
-	err = process.Kill()
-	if err != nil {
-		return fmt.Errorf("failed to kill process: %v", err)
+	for _, pid := range pids {
+		if err := lib.StopProcess(pid); err != nil {
+			return fmt.Errorf("failed to stop process %d: %v", pid, err)
+		}
 	}
 	return nil
 }
 
+// answerReader is shared by all the questions, as a reader of its own would
+// swallow the piped answers of the next ones.
+var answerReader *bufio.Reader
+
+func confirm(cmd *cobra.Command, question string) bool {
+	if assumeYes, _ := cmd.Flags().GetBool("yes"); assumeYes {
+		return true
+	}
+	cmd.Printf("%s [y/N] ", question)
+	if answerReader == nil {
+		answerReader = bufio.NewReader(cmd.InOrStdin())
+	}
+	answers := make(chan string, 1)
+	go func() {
+		answer, _ := answerReader.ReadString('\n')
+		answers <- answer
+	}()
+	var answer string
+	select {
+	case answer = <-answers:
+	case <-cmd.Context().Done():
+		// ctrl+c cancels the context instead of terminating odv.
+		cmd.Println()
+		os.Exit(130)
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// dropLinkedDBs offers to drop the databases (and filestores) linked to
+// deleted branches, and forgets the links of the ones that are kept.
+func dropLinkedDBs(cmd *cobra.Command, branches []string) {
+	for _, branch := range branches {
+		dbname, ok := lib.GetLinkedDB(branch)
+		if !ok {
+			continue
+		}
+		if confirm(cmd, fmt.Sprintf("Drop database %s linked to '%s'?", dbname, branch)) {
+			if err := lib.DropDB(cmd.Context(), dbname); err != nil {
+				cmd.PrintErrf("Failed to drop database %s: %v\n", dbname, err)
+				continue
+			}
+			cmd.Printf("Dropped database: %s\n", dbname)
+		}
+		if err := lib.UnlinkDB(branch); err != nil {
+			cmd.PrintErrf("Failed to unlink database %s: %v\n", dbname, err)
+		}
+	}
+}
+
 var utilsKillOdooCmd = &cobra.Command{
 	Use:   "kill-odoo",
-	Short: "Find and kill the odoo process.",
-	Long:  fmt.Sprintf("Finds the pid of the process listening on port %d and kills it.", lib.GetConfig().OdooPort),
+	Short: "Find and stop the odoo process.",
+	Long:  fmt.Sprintf("Finds the processes listening on port %d, or on the port of the instance given with --name, and stops them, killing them if they do not shut down within the stop timeout.", lib.GetConfig().OdooPort),
 	Run: func(cmd *cobra.Command, args []string) {
-		err := findKillOdooProcess()
+		port := lib.GetConfig().OdooPort
+		if cmd.Flags().Changed("name") {
+			name, _ := cmd.Flags().GetString("name")
+			instance := getInstance(cmd, name)
+			if instance == nil {
+				cmd.PrintErrf("No instance named '%s' is running.\n", name)
+				os.Exit(1)
+			}
+			port = instance.Port
+		}
+		err := findKillOdooProcess(port)
 		if err != nil {
 			cmd.PrintErrln("Failed to kill odoo process:", err)
 			os.Exit(1)
 		}
-		cmd.Println("Odoo process killed successfully.")
+		cmd.Println("Odoo process stopped successfully.")
 	},
 }
 
+type cleanCandidate struct {
+	branch     string
+	presence   []bool // per repository, in sorted order
+	lastCommit time.Time
+	merged     bool
+	gone       bool
+	stale      bool
+}
+
+func (c *cleanCandidate) reasons(staleDays int) string {
+	var reasons []string
+	if c.merged {
+		reasons = append(reasons, "merged")
+	}
+	if c.gone {
+		reasons = append(reasons, "deleted from "+lib.RemoteDev)
+	}
+	if c.stale {
+		reasons = append(reasons, fmt.Sprintf("no commits for %d days", staleDays))
+	}
+	return strings.Join(reasons, ", ")
+}
+
+func (c *cleanCandidate) details(staleDays int) string {
+	return fmt.Sprintf("%s %s",
+		views.FaintStyle.Render(formatTime(c.lastCommit)),
+		views.WarningStyle.Render("("+c.reasons(staleDays)+")"))
+}
+
+func (c *cleanCandidate) render(staleDays int) string {
+	return fmt.Sprintf("%s - %s %s", renderPresence(c.presence), c.branch, c.details(staleDays))
+}
+
+// findCleanCandidates returns the dev branches that look finished: merged
+// into their version branch in every repository having them (a branch without
+// commits of its own is only new, not merged), deleted from the dev remote, or
+// without commits for staleDays (0 disables it).
+func findCleanCandidates(ctx context.Context, staleDays int) ([]*cleanCandidate, error) {
+	type repoBranch struct {
+		lib.BranchInfo
+		merged bool
+	}
+	repoNames := lib.GetSortedRepoNames()
+	found := make([][]repoBranch, len(repoNames))
+	errs := make([]error, len(repoNames))
+	currentBranch := lib.GetCurrentBranch(ctx)
+
+	var wg sync.WaitGroup
+	for i, repoName := range repoNames {
+		if repoName == ".workspace" {
+			continue
+		}
+		wg.Go(func() {
+			repository := lib.GetRepository(repoName)
+			infos, err := repository.GetBranchInfos(ctx)
+			if err != nil {
+				errs[i] = fmt.Errorf("repo '%s': %w", repoName, err)
+				return
+			}
+			for _, info := range infos {
+				if lib.IsVersionBranch(info.Name) || info.Name == lib.FallbackBranch || info.Name == currentBranch {
+					continue
+				}
+				refs, _ := taskBaseRefs(ctx, repoName, repository, info.Name)
+				found[i] = append(found[i], repoBranch{
+					BranchInfo: info,
+					merged: len(refs) > 0 && repository.IsMergedInto(ctx, info.Name, refs...) &&
+						repository.HasOwnCommits(ctx, info.Name, info.Upstream),
+				})
+			}
+		})
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	byBranch := make(map[string]*cleanCandidate)
+	tracked := make(map[string]bool) // has an upstream that still exists somewhere
+	for i, branches := range found {
+		for _, b := range branches {
+			c, ok := byBranch[b.Name]
+			if !ok {
+				c = &cleanCandidate{branch: b.Name, presence: make([]bool, len(repoNames)), merged: true}
+				byBranch[b.Name] = c
+			}
+			c.presence[i] = true
+			c.merged = c.merged && b.merged
+			c.gone = c.gone || b.Gone
+			tracked[b.Name] = tracked[b.Name] || (b.Upstream != "" && !b.Gone)
+			if b.LastCommit.After(c.lastCommit) {
+				c.lastCommit = b.LastCommit
+			}
+		}
+	}
+
+	var candidates []*cleanCandidate
+	staleBefore := time.Now().AddDate(0, 0, -staleDays)
+	for _, c := range byBranch {
+		c.gone = c.gone && !tracked[c.branch]
+		c.stale = staleDays > 0 && c.lastCommit.Before(staleBefore)
+		if c.merged || c.gone || c.stale {
+			candidates = append(candidates, c)
+		}
+	}
+	slices.SortFunc(candidates, func(a, b *cleanCandidate) int { return a.lastCommit.Compare(b.lastCommit) })
+	return candidates, nil
+}
+
+// pruneDevBranches fetches the dev remote so the branches deleted from it
+// are noticed.
+func pruneDevBranches(cmd *cobra.Command) {
+	var states []*views.RepoOperationState
+	for _, repoName := range lib.GetSortedRepoNames() {
+		if repoName != ".workspace" && lib.GetRepository(repoName).HasRemote(cmd.Context(), lib.RemoteDev) {
+			s := views.NewRepoOperationState(repoName)
+			states = append(states, &s)
+		}
+	}
+	if len(states) == 0 {
+		return
+	}
+	_, err := views.RepoBranchSpinnerView{
+		Title:  fmt.Sprintf("Fetching '%s'", lib.RemoteDev),
+		States: states,
+		LaunchOp: func(ctx context.Context, i int) tea.Cmd {
+			return func() tea.Msg {
+				startTime := time.Now()
+				err := lib.GetRepository(states[i].Name).FetchPrune(ctx, lib.RemoteDev)
+				return views.RepoOperationDoneMsg{RepoIndex: i, Err: err, Duration: time.Since(startTime)}
+			}
+		},
+		RenderRepo: func(i int, state *views.RepoOperationState) string {
+			switch state.Status {
+			case views.StatusInProgress:
+				return state.RenderInProgress("fetching")
+			case views.StatusDone:
+				return state.RenderDone("fetched")
+			case views.StatusFailed:
+				return state.RenderFailed("failed to fetch, deleted branches may be missed")
+			}
+			return ""
+		},
+	}.Run(cmd.Context())
+	if err != nil {
+		cmd.PrintErrln(err)
+		os.Exit(1)
+	}
+}
+
+// deleteBranch deletes the branch in all repositories having it, returning
+// whether it was deleted everywhere.
+func deleteBranch(cmd *cobra.Command, branch string) bool {
+	deleted := true
+	for _, repoName := range lib.GetSortedRepoNames() {
+		repository := lib.GetRepository(repoName)
+		if !repository.BranchExists(cmd.Context(), branch) {
+			continue
+		}
+		if err := repository.DeleteBranch(cmd.Context(), branch); err != nil {
+			cmd.PrintErrln(views.RepoLine(repoName, "Failed to delete branch '%s': %v", branch, err))
+			deleted = false
+		} else {
+			cmd.Println(views.RepoLine(repoName, "Deleted branch '%s'", branch))
+		}
+	}
+	return deleted
+}
+
+// orphanWorkspaceBranches returns the .workspace branches that no longer
+// exist in any other repository.
+func orphanWorkspaceBranches(cmd *cobra.Command) []string {
+	branchesToKeep := make(map[string]struct{})
+	branchesToKeep["main"] = struct{}{} // always keep main branch
+	for _, branch := range lib.GetAllBranches(cmd.Context()) {
+		branchesToKeep[branch] = struct{}{}
+	}
+
+	var orphans []string
+	for _, branch := range lib.GetRepository(".workspace").GetBranches(cmd.Context()) {
+		if _, exists := branchesToKeep[branch]; !exists {
+			orphans = append(orphans, branch)
+		}
+	}
+	return orphans
+}
+
+// deleteOrphanWorkspaceBranches deletes the .workspace branches that no
+// longer exist in any other repository.
+func deleteOrphanWorkspaceBranches(cmd *cobra.Command) []string {
+	workspaceRepo := lib.GetRepository(".workspace")
+	var deletedBranches []string
+	for _, branch := range orphanWorkspaceBranches(cmd) {
+		err := workspaceRepo.DeleteBranch(cmd.Context(), branch)
+		if err != nil {
+			cmd.PrintErrf("Failed to delete branch '%s': %v\n", branch, err)
+		} else {
+			cmd.Printf("Deleted orphaned branch '%s'\n", branch)
+			deletedBranches = append(deletedBranches, branch)
+		}
+	}
+	return deletedBranches
+}
+
 var utilsCleanBranchesCmd = &cobra.Command{
 	Use:   "clean-branches",
-	Short: "Clean up local .workspace git branches that have been deleted in other repos.",
+	Short: "Clean up merged, deleted and stale branches in all repos.",
+	Long:  "Finds the dev branches merged into their version branch, deleted from the dev remote or without recent commits, lets you choose which ones to delete in all repositories, then deletes the .workspace branches that no longer exist elsewhere.",
 	Run: func(cmd *cobra.Command, args []string) {
-		workspaceRepo := lib.GetRepository(".workspace")
-		branchesToKeep := make(map[string]struct{})
-		branchesToKeep["main"] = struct{}{} // always keep main branch
-		for _, branch := range lib.GetAllBranches() {
-			branchesToKeep[branch] = struct{}{}
-		}
-
-		var deletedCount int
-		for _, branch := range workspaceRepo.GetBranches() {
-			if _, exists := branchesToKeep[branch]; !exists {
-				err := workspaceRepo.DeleteBranch(branch)
-				if err != nil {
-					cmd.PrintErrf("Failed to delete branch '%s': %v\n", branch, err)
-				} else {
-					cmd.Printf("Deleted orphaned branch '%s'\n", branch)
-					deletedCount++
+		ctx := cmd.Context()
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		assumeYes, _ := cmd.Flags().GetBool("yes")
+		staleDays, _ := cmd.Flags().GetInt("stale-days")
+		if noFetch, _ := cmd.Flags().GetBool("no-fetch"); !noFetch {
+			pruneDevBranches(cmd)
+		}
+
+		candidates, err := findCleanCandidates(ctx, staleDays)
+		if err != nil {
+			cmd.PrintErrf("Failed to list branches: %v\n", err)
+			os.Exit(1)
+		}
+
+		if dryRun {
+			if len(candidates) == 0 {
+				cmd.Println("No branches to clean up.")
+			}
+			for _, c := range candidates {
+				cmd.Println(c.render(staleDays))
+			}
+			for _, branch := range orphanWorkspaceBranches(cmd) {
+				cmd.Printf("Would delete orphaned branch '%s'\n", branch)
+			}
+			return
+		}
+
+		var selected []string
+		switch {
+		case len(candidates) == 0:
+			cmd.Println("No branches to clean up.")
+		case assumeYes:
+			// Stale branches may hold unmerged work, they are only deleted on request.
+			includeStale, _ := cmd.Flags().GetBool("include-stale")
+			for _, c := range candidates {
+				if c.merged || c.gone || includeStale {
+					selected = append(selected, c.branch)
+				}
+			}
+		default:
+			choices := make([]views.BranchChoice, len(candidates))
+			for i, c := range candidates {
+				// Merged or deleted branches are safe to go, stale ones are only suggested.
+				choices[i] = views.BranchChoice{
+					Branch:   c.branch,
+					Detail:   renderPresence(c.presence) + " " + c.details(staleDays),
+					Selected: c.merged || c.gone,
 				}
 			}
+			selected, err = views.BranchMultiSelectView{
+				Title:   "Select the branches to delete",
+				Choices: choices,
+			}.Run()
+			if errors.Is(err, views.ErrNotInteractive) {
+				for _, c := range candidates {
+					cmd.Println(c.render(staleDays))
+				}
+				cmd.PrintErrln("Choosing the branches needs an interactive terminal, pass --yes to delete the merged and deleted ones.")
+				os.Exit(1)
+			} else if err != nil {
+				cmd.PrintErrf("Error running program: %v\n", err)
+				os.Exit(1)
+			}
+			if selected == nil {
+				cmd.Println(views.ListCancelStyle.Render("Cancelled, nothing was deleted."))
+				return
+			}
 		}
+
+		var deletedBranches []string
+		for _, branch := range selected {
+			if deleteBranch(cmd, branch) {
+				deletedBranches = append(deletedBranches, branch)
+			}
+		}
+		deletedBranches = append(deletedBranches, deleteOrphanWorkspaceBranches(cmd)...)
+		dropLinkedDBs(cmd, deletedBranches)
 	},
 }
 
@@ -84,22 +409,21 @@ var utilsDeleteBranchCmd = &cobra.Command{
 	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		branchToDelete := args[0]
-		for repoName, repository := range lib.GetRepositories() {
-			if repository.BranchExists(branchToDelete) {
-				err := repository.DeleteBranch(branchToDelete)
-				if err != nil {
-					cmd.PrintErrln(views.RepoLine(repoName, "Failed to delete branch '%s': %v", branchToDelete, err))
-				} else {
-					cmd.Println(views.RepoLine(repoName, "Deleted branch '%s'", branchToDelete))
-				}
-			}
-		}
+		deleteBranch(cmd, branchToDelete)
+		dropLinkedDBs(cmd, []string{branchToDelete})
 	},
 }
 
 func init() {
+	addInstanceFlag(utilsKillOdooCmd)
 	utilsCmd.AddCommand(utilsKillOdooCmd)
+	utilsCleanBranchesCmd.Flags().BoolP("yes", "y", false, "Delete the merged and deleted branches found and drop their linked databases without asking.")
+	utilsCleanBranchesCmd.Flags().Bool("include-stale", false, "With --yes, delete the stale branches too.")
+	utilsCleanBranchesCmd.Flags().Bool("dry-run", false, "Only list the branches that would be cleaned up.")
+	utilsCleanBranchesCmd.Flags().Int("stale-days", 90, "Also suggest branches without commits for this many days (0 disables it).")
+	utilsCleanBranchesCmd.Flags().Bool("no-fetch", false, "Do not fetch the dev remote to find the branches deleted from it.")
 	utilsCmd.AddCommand(utilsCleanBranchesCmd)
+	utilsDeleteBranchCmd.Flags().BoolP("yes", "y", false, "Drop the linked database without asking.")
 	utilsCmd.AddCommand(utilsDeleteBranchCmd)
 
 	rootCmd.AddCommand(utilsCmd)
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+	"github.com/ziriraha/odv/lib"
+)
+
+// worktreePlan is how a repository gets to its branch in worktree mode, when
+// it does not switch its main checkout in place.
+type worktreePlan struct {
+	path   string // existing checkout of the branch
+	main   bool   // the existing checkout is the main one
+	create bool   // add a worktree for the branch
+	base   string // when creating, create the branch from base too
+}
+
+// planWorktree returns how the repository gets to the branch in worktree
+// mode, or nil when its main checkout must be switched in place: .workspace
+// and version branches without a checkout of their own stay there.
+func planWorktree(ctx context.Context, repoName string, repo *lib.Repository, branch string) (*worktreePlan, error) {
+	if !lib.GetConfig().Worktrees || repoName == ".workspace" {
+		return nil, nil
+	}
+	worktrees, err := repo.GetWorktrees(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if path, ok := worktrees[branch]; ok {
+		return &worktreePlan{path: path, main: lib.SamePath(path, repo.Root())}, nil
+	}
+	if lib.IsVersionBranch(branch) || branch == lib.FallbackBranch {
+		return nil, nil
+	}
+	return &worktreePlan{create: true}, nil
+}
+
+func (p *worktreePlan) apply(ctx context.Context, repo *lib.Repository, branch string) error {
+	if p.create {
+		path, err := repo.AddWorktree(ctx, branch, p.base)
+		if err != nil {
+			return err
+		}
+		p.path = path
+	}
+	repo.SetActivePath(p.path)
+	return nil
+}
+
+// useMainCheckout makes the main checkout active again in worktree mode, for
+// the repositories switched in place.
+func useMainCheckout(repo *lib.Repository) {
+	if lib.GetConfig().Worktrees {
+		repo.SetActivePath(repo.Root())
+	}
+}
+
+func (p *worktreePlan) describe(branch string) string {
+	switch {
+	case p.create:
+		return "created worktree for '" + branch + "'"
+	case p.main:
+		return "using the main checkout on '" + branch + "'"
+	}
+	return "using worktree of '" + branch + "'"
+}
+
+// saveActiveWorktrees records the checkouts that are now active, so the next
+// commands (odv run above all) use them.
+func saveActiveWorktrees(cmd *cobra.Command, branch string) {
+	if !lib.GetConfig().Worktrees {
+		return
+	}
+	if err := lib.SaveActiveWorktrees(branch); err != nil {
+		cmd.PrintErrf("Failed to save the active worktrees: %v\n", err)
+	}
+}
@@ -0,0 +1,266 @@
+package lib
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// The dump layout matches the one produced by Odoo's database manager so
+// backups can be restored from the web interface and vice versa.
+const (
+	dumpSQLName      = "dump.sql"
+	dumpManifestName = "manifest.json"
+	dumpFilestoreDir = "filestore"
+)
+
+type dumpManifest struct {
+	OdooDump     string            `json:"odoo_dump"`
+	DBName       string            `json:"db_name"`
+	Version      string            `json:"version"`
+	MajorVersion string            `json:"major_version"`
+	PGVersion    string            `json:"pg_version"`
+	Modules      map[string]string `json:"modules"`
+}
+
+// pgClientEnv passes the configured connection settings to the PostgreSQL
+// client tools, which are still needed to produce and load SQL dumps.
+func pgClientEnv() []string {
+	cfg := GetConfig().Database
+	env := os.Environ()
+	settings := map[string]string{
+		"PGHOST":     cfg.Host,
+		"PGUSER":     cfg.User,
+		"PGPASSWORD": cfg.Password,
+		"PGSSLMODE":  cfg.SSLMode,
+	}
+	if cfg.Port != 0 {
+		settings["PGPORT"] = strconv.Itoa(cfg.Port)
+	}
+	for key, value := range settings {
+		if value != "" {
+			env = append(env, key+"="+value)
+		}
+	}
+	return env
+}
+
+// odooMajorVersion turns a module version such as "17.0.1.3" or
+// "saas~17.2.1.0" into the server series it belongs to ("17.0", "saas~17.2").
+func odooMajorVersion(moduleVersion string) string {
+	parts := strings.SplitN(moduleVersion, ".", 3)
+	if len(parts) < 2 {
+		return moduleVersion
+	}
+	return parts[0] + "." + parts[1]
+}
+
+func getDumpManifest(ctx context.Context, dbName string) (*dumpManifest, error) {
+	conn, err := connectDB(ctx, dbName)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close(ctx)
+
+	manifest := &dumpManifest{OdooDump: "1", DBName: dbName, Modules: make(map[string]string)}
+	if err := conn.QueryRow(ctx, "SHOW server_version").Scan(&manifest.PGVersion); err != nil {
+		return nil, newDBError("read version of", dbName, err)
+	}
+	rows, err := conn.Query(ctx, "SELECT name, COALESCE(latest_version, '') FROM ir_module_module WHERE state = 'installed'")
+	if err != nil {
+		return nil, newDBError("read modules of", dbName, err)
+	}
+	var name, version string
+	_, err = pgx.ForEachRow(rows, []any{&name, &version}, func() error {
+		manifest.Modules[name] = version
+		return nil
+	})
+	if err != nil {
+		return nil, newDBError("read modules of", dbName, err)
+	}
+	manifest.Version = manifest.Modules["base"]
+	manifest.MajorVersion = odooMajorVersion(manifest.Version)
+	return manifest, nil
+}
+
+func writeDumpSQL(ctx context.Context, archive *zip.Writer, dbName string) error {
+	w, err := archive.Create(dumpSQLName)
+	if err != nil {
+		return err
+	}
+	var stderr strings.Builder
+	dumpCmd := exec.CommandContext(ctx, "pg_dump", "--no-owner", dbName)
+	dumpCmd.Env = pgClientEnv()
+	dumpCmd.Stdout = w
+	dumpCmd.Stderr = &stderr
+	if err := dumpCmd.Run(); err != nil {
+		return fmt.Errorf("pg_dump failed: %w: %s", err, stderr.String())
+	}
+	return nil
+}
+
+func writeDumpFilestore(archive *zip.Writer, dbName string) error {
+	filestore := filepath.Join(GetFilestorePath(), dbName)
+	if _, err := os.Stat(filestore); os.IsNotExist(err) {
+		return nil
+	}
+	return filepath.WalkDir(filestore, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		relPath, err := filepath.Rel(filestore, filePath)
+		if err != nil {
+			return err
+		}
+		w, err := archive.Create(path.Join(dumpFilestoreDir, filepath.ToSlash(relPath)))
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(filePath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(w, f)
+		return err
+	})
+}
+
+func BackupDB(ctx context.Context, dbName, zipPath string) (err error) {
+	manifest, err := getDumpManifest(ctx, dbName)
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(zipPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create backup file: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(zipPath)
+		}
+	}()
+	defer out.Close()
+
+	archive := zip.NewWriter(out)
+	w, err := archive.Create(dumpManifestName)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "    ")
+	if err := encoder.Encode(manifest); err != nil {
+		return err
+	}
+	if err := writeDumpSQL(ctx, archive, dbName); err != nil {
+		return err
+	}
+	if err := writeDumpFilestore(archive, dbName); err != nil {
+		return fmt.Errorf("failed to add filestore to backup: %w", err)
+	}
+	return archive.Close()
+}
+
+func loadDumpSQL(ctx context.Context, dump *zip.File, dbName string) error {
+	r, err := dump.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	var stderr strings.Builder
+	loadCmd := exec.CommandContext(ctx, "psql", "--quiet", "--no-psqlrc", "-v", "ON_ERROR_STOP=1", "-d", dbName, "-f", "-")
+	loadCmd.Env = pgClientEnv()
+	loadCmd.Stdin = r
+	loadCmd.Stderr = &stderr
+	if err := loadCmd.Run(); err != nil {
+		return fmt.Errorf("psql failed to load dump: %w: %s", err, stderr.String())
+	}
+	return nil
+}
+
+func extractDumpFilestore(archive *zip.Reader, dbName string) error {
+	filestore := filepath.Join(GetFilestorePath(), dbName)
+	for _, file := range archive.File {
+		relPath, ok := strings.CutPrefix(file.Name, dumpFilestoreDir+"/")
+		if !ok || relPath == "" || strings.HasSuffix(relPath, "/") {
+			continue
+		}
+		if !filepath.IsLocal(relPath) {
+			return fmt.Errorf("invalid filestore path in backup: %s", file.Name)
+		}
+		target := filepath.Join(filestore, filepath.FromSlash(relPath))
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		if err := extractZipFile(file, target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func extractZipFile(file *zip.File, target string) error {
+	r, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	w, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	_, err = io.Copy(w, r)
+	return err
+}
+
+func RestoreDB(ctx context.Context, zipPath, dbName string) (err error) {
+	archive, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer archive.Close()
+
+	var dump *zip.File
+	for _, file := range archive.File {
+		if file.Name == dumpSQLName {
+			dump = file
+		}
+	}
+	if dump == nil {
+		return errors.New("backup does not contain " + dumpSQLName)
+	}
+	if _, err := os.Stat(filepath.Join(GetFilestorePath(), dbName)); err == nil {
+		return fmt.Errorf("filestore for new database %s already exists", dbName)
+	}
+
+	if err := CreateDB(ctx, dbName); err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			DropDB(context.WithoutCancel(ctx), dbName) // don't leave a half restored database behind
+		}
+	}()
+	if err := loadDumpSQL(ctx, dump, dbName); err != nil {
+		return err
+	}
+	if err := extractDumpFilestore(&archive.Reader, dbName); err != nil {
+		return fmt.Errorf("failed to restore filestore: %w", err)
+	}
+	return nil
+}
@@ -8,18 +8,48 @@ import (
 	"github.com/pelletier/go-toml/v2"
 )
 
+type DatabaseConfig struct {
+	Host     string `toml:"host"`
+	Port     int    `toml:"port"`
+	User     string `toml:"user"`
+	Password string `toml:"password"`
+	SSLMode  string `toml:"sslmode"`
+}
+
+// Timeouts are in seconds, 0 disables them.
+type TimeoutsConfig struct {
+	Git     int `toml:"git"`
+	Network int `toml:"network"`
+	DB      int `toml:"db"`
+	Stop    int `toml:"stop"` // grace period given to Odoo to shut down before it is killed
+}
+
 type Config struct {
 	OdooHome     string            `toml:"odoo_home"`
 	DBPrefix     string            `toml:"db_prefix"`
 	OdooPort     int               `toml:"odoo_port"`
+	PortRange    [2]int            `toml:"port_range"` // ports of the named instances
+	Autostash    bool              `toml:"autostash"`
+	BranchStash  bool              `toml:"branch_stash"`
+	Worktrees    bool              `toml:"worktrees"`
+	WorktreeDir  string            `toml:"worktree_dir"`
+	Database     DatabaseConfig    `toml:"database"`
+	Timeouts     TimeoutsConfig    `toml:"timeouts"`
 	Repositories map[string]string `toml:"repositories"`
 }
 
 func getDefaultConfig() Config {
 	return Config{
-		OdooHome: "$ODOO_HOME",
-		DBPrefix: "rd-",
-		OdooPort: 8069,
+		OdooHome:  "$ODOO_HOME",
+		DBPrefix:  "rd-",
+		OdooPort:  8069,
+		PortRange: [2]int{8100, 8199},
+		Timeouts: TimeoutsConfig{
+			Git:     60,
+			Network: 300,
+			DB:      600,
+			Stop:    10,
+		},
 		Repositories: map[string]string{
 			".workspace": ".workspace",
 			"community":  "community",
@@ -62,6 +92,10 @@ func GetConfig() *Config {
 		if cfg.OdooHome == "" {
 			cfg.OdooHome = "."
 		}
+		cfg.WorktreeDir = os.ExpandEnv(cfg.WorktreeDir)
+		if cfg.WorktreeDir == "" {
+			cfg.WorktreeDir = filepath.Join(cfg.OdooHome, "worktrees")
+		}
 
 		userConfig = &cfg
 	})
@@ -1,13 +1,55 @@
 package lib
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	ErrDBNotFound = errors.New("database does not exist")
+	ErrDBExists   = errors.New("database already exists")
+	ErrDBInUse    = errors.New("database is being accessed by other users")
 )
 
+// DBError describes a failed operation on a database. Err is one of the
+// ErrDB* sentinels when the server error is a known one.
+type DBError struct {
+	Op     string
+	DBName string
+	Err    error
+}
+
+func (e *DBError) Error() string {
+	return fmt.Sprintf("failed to %s database %s: %v", e.Op, e.DBName, e.Err)
+}
+
+func (e *DBError) Unwrap() error { return e.Err }
+
+func newDBError(op, dbName string, err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "3D000": // invalid_catalog_name
+			err = ErrDBNotFound
+		case "42P04": // duplicate_database
+			err = ErrDBExists
+		case "55006": // object_in_use
+			err = ErrDBInUse
+		}
+	}
+	return &DBError{Op: op, DBName: dbName, Err: err}
+}
+
 var (
 	filestorePath     string
 	filestorePathOnce sync.Once
@@ -28,55 +70,122 @@ func GetFilestorePath() string {
 	return filestorePath
 }
 
-var DBMutex sync.Mutex
+var connValueEscaper = strings.NewReplacer(`\`, `\\`, "'", `\'`)
+
+// getDBConnString builds a libpq keyword/value connection string. Settings left
+// empty in the odvrc fall back to the PG* environment variables and defaults.
+func getDBConnString(dbName string) string {
+	cfg := GetConfig().Database
+	port := ""
+	if cfg.Port != 0 {
+		port = strconv.Itoa(cfg.Port)
+	}
+	settings := []struct{ key, value string }{
+		{"host", cfg.Host},
+		{"port", port},
+		{"user", cfg.User},
+		{"password", cfg.Password},
+		{"sslmode", cfg.SSLMode},
+		{"dbname", dbName},
+	}
 
-func runDBCommand(name string, args ...string) (string, error) {
-	DBMutex.Lock()
-	defer DBMutex.Unlock()
-	return runCommand(name, args...)
+	var parts []string
+	for _, setting := range settings {
+		if setting.value != "" {
+			parts = append(parts, fmt.Sprintf("%s='%s'", setting.key, connValueEscaper.Replace(setting.value)))
+		}
+	}
+	return strings.Join(parts, " ")
 }
 
-func DropDB(dbName string) error {
-	_, err := runDBCommand("dropdb", "--if-exists", "--force", dbName)
+func connectDB(ctx context.Context, dbName string) (*pgx.Conn, error) {
+	connConfig, err := pgx.ParseConfig(getDBConnString(dbName))
 	if err != nil {
-		return fmt.Errorf("failed to drop database %s: %v", dbName, err)
+		return nil, fmt.Errorf("invalid database configuration: %w", err)
 	}
-	err = os.RemoveAll(GetFilestorePath() + "/" + dbName)
+	conn, err := pgx.ConnectConfig(ctx, connConfig)
 	if err != nil {
-		return fmt.Errorf("failed to remove filestore for database %s: %v", dbName, err)
+		return nil, newDBError("connect to", dbName, err)
+	}
+	return conn, nil
+}
+
+func execMaintenance(ctx context.Context, op, dbName, sql string, args ...any) error {
+	ctx, cancel := withTimeout(ctx, GetConfig().Timeouts.DB)
+	defer cancel()
+	conn, err := connectDB(ctx, "postgres")
+	if err != nil {
+		return err
+	}
+	defer conn.Close(ctx)
+	if _, err := conn.Exec(ctx, sql, args...); err != nil {
+		return newDBError(op, dbName, err)
 	}
 	return nil
 }
 
-func CreateDB(dbName string) error {
-	_, err := runDBCommand("createdb", dbName)
-	return err
+func quoteIdent(name string) string {
+	return pgx.Identifier{name}.Sanitize()
 }
 
-func DuplicateDB(sourceDB, newDB string) error {
-	_, err := runDBCommand("createdb", "-T", sourceDB, newDB)
+func DropDB(ctx context.Context, dbName string) error {
+	err := execMaintenance(ctx, "drop", dbName, "DROP DATABASE IF EXISTS "+quoteIdent(dbName)+" WITH (FORCE)")
+	if err != nil {
+		return err
+	}
+	err = os.RemoveAll(filepath.Join(GetFilestorePath(), dbName))
 	if err != nil {
-		return fmt.Errorf("failed to create database %s from template %s: %v", newDB, sourceDB, err)
+		return fmt.Errorf("failed to remove filestore for database %s: %v", dbName, err)
 	}
-	sourceFilestore := GetFilestorePath() + "/" + sourceDB
-	newFilestore := GetFilestorePath() + "/" + newDB
+	return forgetDB(dbName)
+}
+
+func CreateDB(ctx context.Context, dbName string) error {
+	return execMaintenance(ctx, "create", dbName, "CREATE DATABASE "+quoteIdent(dbName))
+}
+
+func DuplicateDB(ctx context.Context, sourceDB, newDB string) error {
+	sourceFilestore := filepath.Join(GetFilestorePath(), sourceDB)
+	newFilestore := filepath.Join(GetFilestorePath(), newDB)
 	if _, err := os.Stat(newFilestore); err == nil {
 		return fmt.Errorf("filestore for new database %s already exists", newDB)
 	}
+
+	// A template database can't have other sessions, so disconnect them first.
+	err := execMaintenance(ctx, "disconnect from", sourceDB,
+		"SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE datname = $1 AND pid != pg_backend_pid()", sourceDB)
+	if err != nil {
+		return err
+	}
+	// Both names, the failure may come from either (missing source, existing copy).
+	err = execMaintenance(ctx, "duplicate", sourceDB+" -> "+newDB, "CREATE DATABASE "+quoteIdent(newDB)+" WITH TEMPLATE "+quoteIdent(sourceDB))
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(sourceFilestore); os.IsNotExist(err) {
+		return nil
+	}
 	return os.CopyFS(newFilestore, os.DirFS(sourceFilestore))
 }
 
-func ListDBs(prefix string) ([]string, error) {
-	output, err := runDBCommand("psql", "-d", "postgres", "-t", "-c", "SELECT datname FROM pg_database WHERE datname LIKE '"+prefix+"%';")
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func ListDBs(ctx context.Context, prefix string) ([]string, error) {
+	ctx, cancel := withTimeout(ctx, GetConfig().Timeouts.DB)
+	defer cancel()
+	conn, err := connectDB(ctx, "postgres")
 	if err != nil {
 		return nil, err
 	}
-	var dbs []string
-	for line := range strings.SplitSeq(string(output), "\n") {
-		dbName := strings.TrimSpace(line)
-		if dbName != "" {
-			dbs = append(dbs, dbName)
-		}
+	defer conn.Close(ctx)
+
+	rows, err := conn.Query(ctx, "SELECT datname FROM pg_database WHERE datname LIKE $1 ORDER BY datname", likeEscaper.Replace(prefix)+"%")
+	if err != nil {
+		return nil, newDBError("list", prefix+"*", err)
+	}
+	dbs, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, newDBError("list", prefix+"*", err)
 	}
 	return dbs, nil
 }
@@ -0,0 +1,97 @@
+package lib
+
+import (
+	"context"
+	"io/fs"
+	"path/filepath"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type DBInfo struct {
+	Name             string `json:"name"`
+	IsOdoo           bool   `json:"is_odoo"`
+	Version          string `json:"version"`
+	InstalledModules int    `json:"installed_modules"`
+	UUID             string `json:"uuid"`
+	CreateDate       string `json:"create_date"`
+	BaseURL          string `json:"base_url"`
+	Size             int64  `json:"size"`
+	FilestoreSize    int64  `json:"filestore_size"`
+	// LastModified is the latest of the last login and the last filestore write.
+	LastModified time.Time `json:"last_modified"`
+}
+
+func getFilestoreStats(dbName string) (size int64, lastModified time.Time) {
+	filestore := filepath.Join(GetFilestorePath(), dbName)
+	filepath.WalkDir(filestore, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return nil
+		}
+		if info, err := entry.Info(); err == nil {
+			size += info.Size()
+			if info.ModTime().After(lastModified) {
+				lastModified = info.ModTime()
+			}
+		}
+		return nil
+	})
+	return size, lastModified
+}
+
+func GetDBInfo(ctx context.Context, dbName string) (*DBInfo, error) {
+	ctx, cancel := withTimeout(ctx, GetConfig().Timeouts.DB)
+	defer cancel()
+	conn, err := connectDB(ctx, dbName)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close(ctx)
+
+	info := &DBInfo{Name: dbName}
+	info.FilestoreSize, info.LastModified = getFilestoreStats(dbName)
+	err = conn.QueryRow(ctx, "SELECT pg_database_size(current_database()), to_regclass('ir_module_module') IS NOT NULL").Scan(&info.Size, &info.IsOdoo)
+	if err != nil {
+		return nil, newDBError("read size of", dbName, err)
+	}
+	if !info.IsOdoo {
+		return info, nil
+	}
+
+	var baseVersion string
+	err = conn.QueryRow(ctx, `SELECT COALESCE((SELECT latest_version FROM ir_module_module WHERE name = 'base'), ''),
+		(SELECT COUNT(*) FROM ir_module_module WHERE state = 'installed')`).Scan(&baseVersion, &info.InstalledModules)
+	if err != nil {
+		return nil, newDBError("read modules of", dbName, err)
+	}
+	info.Version = odooMajorVersion(baseVersion)
+
+	rows, err := conn.Query(ctx, "SELECT key, value FROM ir_config_parameter WHERE key IN ('database.uuid', 'database.create_date', 'web.base.url')")
+	if err != nil {
+		return nil, newDBError("read parameters of", dbName, err)
+	}
+	var key, value string
+	_, err = pgx.ForEachRow(rows, []any{&key, &value}, func() error {
+		switch key {
+		case "database.uuid":
+			info.UUID = value
+		case "database.create_date":
+			info.CreateDate = value
+		case "web.base.url":
+			info.BaseURL = value
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, newDBError("read parameters of", dbName, err)
+	}
+
+	var lastLogin *time.Time
+	if err := conn.QueryRow(ctx, "SELECT MAX(create_date) FROM res_users_log").Scan(&lastLogin); err == nil && lastLogin != nil {
+		if lastLogin.After(info.LastModified) {
+			info.LastModified = *lastLogin
+		}
+	}
+	return info, nil
+}
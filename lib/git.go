@@ -1,10 +1,15 @@
 package lib
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"path/filepath"
 	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 )
 
 const (
@@ -16,31 +21,46 @@ const (
 type Repository struct {
 	lock            sync.RWMutex
 	getBranchesOnce sync.Once
-	path            string
+	folder          string // folder name in odoo_home, also used in worktree paths
+	root            string // main checkout
+	path            string // active checkout, the main one or a worktree
 	Color           func(format string, a ...any) string
 	branches        []string
 }
 
-func (r *Repository) readCommand(args ...string) (string, error) {
+func (r *Repository) runGit(ctx context.Context, timeoutSeconds int, args ...string) (string, error) {
+	ctx, cancel := withTimeout(ctx, timeoutSeconds)
+	defer cancel()
+	return runCommand(ctx, "git", append([]string{"-C", r.path}, args...)...)
+}
+
+func (r *Repository) readCommand(ctx context.Context, args ...string) (string, error) {
 	r.lock.RLock()
 	defer r.lock.RUnlock()
-	return runCommand("git", append([]string{"-C", r.path}, args...)...)
+	return r.runGit(ctx, GetConfig().Timeouts.Git, args...)
 }
 
-func (r *Repository) writeCommand(args ...string) error {
+func (r *Repository) writeCommand(ctx context.Context, args ...string) error {
 	r.lock.Lock()
 	defer r.lock.Unlock()
-	_, err := runCommand("git", append([]string{"-C", r.path}, args...)...)
+	_, err := r.runGit(ctx, GetConfig().Timeouts.Git, args...)
 	return err
 }
 
-func (r *Repository) GetBranches() []string {
+// networkCommand is a writeCommand talking to a remote, which gets the
+// (longer) network timeout.
+func (r *Repository) networkCommand(ctx context.Context, args ...string) (string, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.runGit(ctx, GetConfig().Timeouts.Network, args...)
+}
+
+func (r *Repository) GetBranches(ctx context.Context) []string {
 	r.getBranchesOnce.Do(func() {
-		output, err := r.readCommand("branch")
+		output, err := r.readCommand(ctx, "branch", "--format=%(refname:short)")
 		if err == nil {
 			for line := range strings.SplitSeq(output, "\n") {
 				line = strings.TrimSpace(line)
-				line = strings.TrimPrefix(line, "* ")
 				if line != "" {
 					r.branches = append(r.branches, line)
 				}
@@ -50,41 +70,45 @@ func (r *Repository) GetBranches() []string {
 	return slices.Clone(r.branches)
 }
 
-func (r *Repository) BranchExists(branchName string) bool {
-	return slices.Contains(r.GetBranches(), branchName)
+func (r *Repository) BranchExists(ctx context.Context, branchName string) bool {
+	return slices.Contains(r.GetBranches(ctx), branchName)
 }
 
-func (r *Repository) SwitchBranch(branchName string) error {
-	return r.writeCommand("switch", branchName)
+func (r *Repository) SwitchBranch(ctx context.Context, branchName string) error {
+	return r.writeCommand(ctx, "switch", branchName)
 }
 
-func (r *Repository) CreateBranchFrom(baseBranch, newBranch string) error {
-	err := r.writeCommand("switch", "-c", newBranch, baseBranch)
+func (r *Repository) CreateBranchFrom(ctx context.Context, baseBranch, newBranch string) error {
+	err := r.writeCommand(ctx, "switch", "-c", newBranch, baseBranch)
 	if err == nil {
 		r.getBranchesOnce = sync.Once{} // reset so branches will be reloaded on next GetBranches call
 	}
 	return err
 }
 
-func (r *Repository) DeleteBranch(branchName string) error {
-	err := r.writeCommand("branch", "-D", branchName)
+// DeleteBranch deletes the branch, and its worktree first when it has one.
+func (r *Repository) DeleteBranch(ctx context.Context, branchName string) error {
+	if err := r.RemoveWorktree(ctx, branchName); err != nil {
+		return err
+	}
+	err := r.writeCommand(ctx, "branch", "-D", branchName)
 	if err == nil {
 		r.getBranchesOnce = sync.Once{} // reset so branches will be reloaded on next GetBranches call
 	}
 	return err
 }
 
-func (r *Repository) GetCurrentBranch() string {
-	output, err := r.readCommand("branch", "--show-current")
+func (r *Repository) GetCurrentBranch(ctx context.Context) string {
+	output, err := r.readCommand(ctx, "branch", "--show-current")
 	if err != nil {
 		panic(fmt.Errorf("GetCurrentBranch error: %v", err))
 	}
 	return strings.TrimSpace(output)
 }
 
-func (r *Repository) GetStatus() ([]string, error) {
+func (r *Repository) GetStatus(ctx context.Context) ([]string, error) {
 	var changes []string
-	output, err := r.readCommand("status", "--porcelain")
+	output, err := r.readCommand(ctx, "status", "--porcelain")
 	if err != nil {
 		return changes, err
 	}
@@ -97,8 +121,8 @@ func (r *Repository) GetStatus() ([]string, error) {
 	return changes, nil
 }
 
-func (r *Repository) GetAheadBehindInfo(remote, branch string) (ahead int, behind int, err error) {
-	output, err := r.readCommand("rev-list", "--left-right", "--count", fmt.Sprintf("%s...%s/%s", branch, remote, branch))
+func (r *Repository) GetAheadBehindInfo(ctx context.Context, remote, branch string) (ahead int, behind int, err error) {
+	output, err := r.readCommand(ctx, "rev-list", "--left-right", "--count", fmt.Sprintf("%s...%s/%s", branch, remote, branch))
 	if err != nil {
 		return -1, -1, err
 	}
@@ -111,18 +135,421 @@ func (r *Repository) GetAheadBehindInfo(remote, branch string) (ahead int, behin
 	return ahead, behind, nil
 }
 
-func (r *Repository) PullRebase(remote, branch string) error {
-	return r.writeCommand("pull", "--rebase", remote, branch)
+func (r *Repository) PullRebase(ctx context.Context, remote, branch string) error {
+	_, err := r.networkCommand(ctx, "pull", "--rebase", remote, branch)
+	return err
+}
+
+func (r *Repository) FetchRefspec(ctx context.Context, remote, branch string) error {
+	_, err := r.networkCommand(ctx, "fetch", remote, fmt.Sprintf("%s:%s", branch, branch))
+	return err
+}
+
+// GetRemoteBranches returns the branches of the remote known locally, from
+// its remote-tracking branches.
+func (r *Repository) GetRemoteBranches(ctx context.Context, remote string) []string {
+	var branches []string
+	output, err := r.readCommand(ctx, "branch", "-r", "--format=%(refname:short)")
+	if err != nil {
+		return branches
+	}
+	for line := range strings.SplitSeq(output, "\n") {
+		branch, ok := strings.CutPrefix(strings.TrimSpace(line), remote+"/")
+		if ok && branch != "HEAD" {
+			branches = append(branches, branch)
+		}
+	}
+	return branches
+}
+
+// FetchTrackingBranch fetches the branch from the remote and creates a local
+// branch tracking it. It returns false when the remote has no such branch.
+func (r *Repository) FetchTrackingBranch(ctx context.Context, remote, branch string) (bool, error) {
+	output, err := r.networkCommand(ctx, "ls-remote", "--heads", remote, "refs/heads/"+branch)
+	if err != nil {
+		return false, err
+	}
+	if strings.TrimSpace(output) == "" {
+		return false, nil
+	}
+	trackingRef := fmt.Sprintf("refs/remotes/%s/%s", remote, branch)
+	if _, err := r.networkCommand(ctx, "fetch", remote, fmt.Sprintf("+refs/heads/%s:%s", branch, trackingRef)); err != nil {
+		return true, err
+	}
+	err = r.writeCommand(ctx, "branch", "--track", branch, fmt.Sprintf("%s/%s", remote, branch))
+	if err == nil {
+		r.getBranchesOnce = sync.Once{} // reset so branches will be reloaded on next GetBranches call
+	}
+	return true, err
+}
+
+type BranchInfo struct {
+	Name       string
+	LastCommit time.Time
+	Upstream   string
+	Gone       bool // the upstream branch was deleted from its remote
+}
+
+// GetBranchInfos returns the local branches with their last commit date and
+// upstream. Deleted upstreams are only noticed once pruned by a fetch.
+func (r *Repository) GetBranchInfos(ctx context.Context) ([]BranchInfo, error) {
+	output, err := r.readCommand(ctx, "for-each-ref", "--format=%(refname:short)%00%(committerdate:unix)%00%(upstream:short)%00%(upstream:track)", "refs/heads")
+	if err != nil {
+		return nil, err
+	}
+	var infos []BranchInfo
+	for line := range strings.SplitSeq(strings.TrimSpace(output), "\n") {
+		fields := strings.Split(line, "\x00")
+		if len(fields) < 4 {
+			continue
+		}
+		timestamp, _ := strconv.ParseInt(fields[1], 10, 64)
+		infos = append(infos, BranchInfo{
+			Name:       fields[0],
+			LastCommit: time.Unix(timestamp, 0),
+			Upstream:   fields[2],
+			Gone:       fields[3] == "[gone]",
+		})
+	}
+	return infos, nil
+}
+
+// IsMergedInto tells whether the branch is merged into any of the refs.
+func (r *Repository) IsMergedInto(ctx context.Context, branch string, refs ...string) bool {
+	for _, ref := range refs {
+		if _, err := r.readCommand(ctx, "merge-base", "--is-ancestor", branch, ref); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// HasOwnCommits tells whether the branch moved since it was created, going
+// by the oldest entry of its reflog, so that a branch without commits of its
+// own is not taken for merged. Without a reflog, a branch having an upstream
+// is assumed to have some.
+func (r *Repository) HasOwnCommits(ctx context.Context, branch, upstream string) bool {
+	output, err := r.readCommand(ctx, "log", "-g", "--format=%H", "refs/heads/"+branch, "--")
+	entries := strings.Fields(output)
+	if err != nil || len(entries) == 0 {
+		return upstream != ""
+	}
+	tip, err := r.readCommand(ctx, "rev-parse", "refs/heads/"+branch)
+	return err == nil && strings.TrimSpace(tip) != entries[len(entries)-1]
+}
+
+func (r *Repository) HasRemote(ctx context.Context, remote string) bool {
+	output, err := r.readCommand(ctx, "remote")
+	return err == nil && slices.Contains(strings.Fields(output), remote)
+}
+
+// FetchPrune fetches the remote, pruning the remote-tracking branches of the
+// branches deleted from it.
+func (r *Repository) FetchPrune(ctx context.Context, remote string) error {
+	_, err := r.networkCommand(ctx, "fetch", "--prune", remote)
+	return err
+}
+
+// IsRebasing tells whether a rebase was stopped in the repository, waiting for
+// --continue, --skip or --abort.
+func (r *Repository) IsRebasing(ctx context.Context) bool {
+	for _, dir := range []string{"rebase-merge", "rebase-apply"} {
+		output, err := r.readCommand(ctx, "rev-parse", "--git-path", dir)
+		if err != nil {
+			return false
+		}
+		path := strings.TrimSpace(output)
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(r.path, path)
+		}
+		if _, err := os.Stat(path); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Repository) RebaseContinue(ctx context.Context) error {
+	// Keep the original commit messages instead of waiting on an editor.
+	return r.writeCommand(ctx, "-c", "core.editor=true", "rebase", "--continue")
+}
+
+func (r *Repository) RebaseSkip(ctx context.Context) error {
+	return r.writeCommand(ctx, "rebase", "--skip")
 }
 
-func (r *Repository) FetchRefspec(remote, branch string) error {
-	return r.writeCommand("fetch", remote, fmt.Sprintf("%s:%s", branch, branch))
+func (r *Repository) RebaseAbort(ctx context.Context) error {
+	return r.writeCommand(ctx, "rebase", "--abort")
 }
 
-func (r *Repository) CommitAll(message string) error {
-	err := r.writeCommand("add", ".")
+func IsConflictStatus(status string) bool {
+	switch status {
+	case "UU", "AA", "DD", "AU", "UA", "DU", "UD":
+		return true
+	}
+	return false
+}
+
+func (r *Repository) GetConflicts(ctx context.Context) ([]FileChange, error) {
+	changes, err := r.GetStatus(ctx)
 	if err != nil {
+		return nil, err
+	}
+	var conflicts []FileChange
+	for _, line := range changes {
+		change := ParseStatusLine(line)
+		if IsConflictStatus(change.Index + change.Worktree) {
+			conflicts = append(conflicts, change)
+		}
+	}
+	return conflicts, nil
+}
+
+func (r *Repository) GetConflictDiff(ctx context.Context, path string) (string, error) {
+	return r.readCommand(ctx, "diff", "--", path)
+}
+
+func (r *Repository) MarkResolved(ctx context.Context, path string) error {
+	return r.writeCommand(ctx, "add", "--", path)
+}
+
+// TakeConflictSide resolves a conflict with one side of it. During a rebase,
+// "ours" is the branch being rebased on and "theirs" the commit being replayed.
+func (r *Repository) TakeConflictSide(ctx context.Context, path string, theirs bool) error {
+	side := "--ours"
+	if theirs {
+		side = "--theirs"
+	}
+	if err := r.writeCommand(ctx, "checkout", side, "--", path); err != nil {
 		return err
 	}
-	return r.writeCommand("commit", "-m", message)
+	return r.MarkResolved(ctx, path)
+}
+
+type PushResult int
+
+const (
+	PushUpToDate PushResult = iota
+	PushCreated
+	PushUpdated
+	PushForced
+	PushRejected
+)
+
+// Push pushes the branch to the same name on the remote and sets it as
+// upstream. The result comes from the ref status of `git push --porcelain`.
+func (r *Repository) Push(ctx context.Context, remote, branch string, forceWithLease bool) (PushResult, error) {
+	args := []string{"push", "--porcelain", "--set-upstream"}
+	if forceWithLease {
+		args = append(args, "--force-with-lease")
+	}
+	output, err := r.networkCommand(ctx, append(args, remote, fmt.Sprintf("%s:%s", branch, branch))...)
+
+	result := PushUpToDate
+	for line := range strings.SplitSeq(output, "\n") {
+		if len(line) < 2 || line[1] != '\t' {
+			continue
+		}
+		switch line[0] {
+		case '*':
+			result = PushCreated
+		case ' ':
+			result = PushUpdated
+		case '+':
+			result = PushForced
+		case '!':
+			result = PushRejected
+		}
+	}
+	if result == PushRejected {
+		return result, fmt.Errorf("push to %s/%s was rejected, the remote branch has commits that are not local", remote, branch)
+	}
+	return result, err
+}
+
+// Stash stashes the changes to tracked files (and untracked ones when asked),
+// returning the stash commit, or "" when there was nothing to stash.
+func (r *Repository) Stash(ctx context.Context, message string, includeUntracked bool) (string, error) {
+	before, _ := r.readCommand(ctx, "rev-parse", "-q", "--verify", "refs/stash")
+	args := []string{"stash", "push", "-m", message}
+	if includeUntracked {
+		args = append(args, "--include-untracked")
+	}
+	if err := r.writeCommand(ctx, args...); err != nil {
+		return "", err
+	}
+	after, _ := r.readCommand(ctx, "rev-parse", "-q", "--verify", "refs/stash")
+	if after == before {
+		return "", nil
+	}
+	return strings.TrimSpace(after), nil
+}
+
+// StashRef finds the stash@{n} name of a stash commit, which shifts as other
+// stashes are pushed and dropped.
+func (r *Repository) StashRef(ctx context.Context, commit string) (string, error) {
+	output, err := r.readCommand(ctx, "stash", "list", "--format=%H")
+	if err != nil {
+		return "", err
+	}
+	for i, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		if line == commit {
+			return fmt.Sprintf("stash@{%d}", i), nil
+		}
+	}
+	return "", fmt.Errorf("stash %s not found", commit)
+}
+
+// StashPop restores a stash commit. When it conflicts, git keeps the stash.
+func (r *Repository) StashPop(ctx context.Context, commit string) error {
+	ref, err := r.StashRef(ctx, commit)
+	if err != nil {
+		return err
+	}
+	return r.writeCommand(ctx, "stash", "pop", ref)
+}
+
+func (r *Repository) StashDrop(ctx context.Context, commit string) error {
+	ref, err := r.StashRef(ctx, commit)
+	if err != nil {
+		return err
+	}
+	return r.writeCommand(ctx, "stash", "drop", ref)
+}
+
+func (r *Repository) CommitAll(ctx context.Context, message string) error {
+	err := r.writeCommand(ctx, "add", ".")
+	if err != nil {
+		return err
+	}
+	return r.writeCommand(ctx, "commit", "-m", message)
+}
+
+// Path returns the active checkout of the repository.
+func (r *Repository) Path() string {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.path
+}
+
+type Commit struct {
+	Hash    string    `json:"hash"`
+	Author  string    `json:"author"`
+	Date    time.Time `json:"date"`
+	Subject string    `json:"subject"`
+	Stat    string    `json:"stat,omitempty"`
+}
+
+func (r *Repository) RefExists(ctx context.Context, ref string) bool {
+	_, err := r.readCommand(ctx, "rev-parse", "--verify", "-q", ref)
+	return err == nil
+}
+
+// GetLog returns the commits selected by the revisions (e.g. HEAD ^master),
+// newest first, with their diffstat when asked. A limit of 0 returns all of them.
+func (r *Repository) GetLog(ctx context.Context, revs []string, limit int, stat bool) ([]Commit, error) {
+	args := []string{"log", "--format=%x1e%H%x00%an%x00%aI%x00%s"}
+	if limit > 0 {
+		args = append(args, fmt.Sprintf("--max-count=%d", limit))
+	}
+	if stat {
+		args = append(args, "--stat")
+	}
+	args = append(args, revs...)
+	output, err := r.readCommand(ctx, append(args, "--")...)
+	if err != nil {
+		return nil, err
+	}
+
+	var commits []Commit
+	for record := range strings.SplitSeq(output, "\x1e") {
+		header, statOutput, _ := strings.Cut(record, "\n")
+		fields := strings.SplitN(header, "\x00", 4)
+		if len(fields) < 4 {
+			continue
+		}
+		date, _ := time.Parse(time.RFC3339, fields[2])
+		commits = append(commits, Commit{
+			Hash:    fields[0],
+			Author:  fields[1],
+			Date:    date,
+			Subject: fields[3],
+			Stat:    strings.Trim(statOutput, "\n"),
+		})
+	}
+	return commits, nil
+}
+
+// MergeBase returns the best common ancestor of HEAD and the refs, the point
+// where HEAD left the most recent of them.
+func (r *Repository) MergeBase(ctx context.Context, refs ...string) (string, error) {
+	output, err := r.readCommand(ctx, append([]string{"merge-base", "HEAD"}, refs...)...)
+	return strings.TrimSpace(output), err
+}
+
+type DiffOptions struct {
+	Base   string // diff the commits since base instead of the local changes
+	Staged bool   // diff the staged changes instead of the unstaged ones
+	Stat   bool
+	Color  bool
+	Prefix string // prefixed to the paths, after a/ and b/
+}
+
+func (r *Repository) GetDiff(ctx context.Context, opts DiffOptions) (string, error) {
+	args := []string{"diff", "--color=never"}
+	if opts.Color {
+		args[1] = "--color=always"
+	}
+	if opts.Stat {
+		args = append(args, "--stat")
+	}
+	if opts.Prefix != "" {
+		args = append(args, "--src-prefix=a/"+opts.Prefix+"/", "--dst-prefix=b/"+opts.Prefix+"/")
+	}
+	switch {
+	case opts.Base != "":
+		args = append(args, opts.Base, "HEAD")
+	case opts.Staged:
+		args = append(args, "--cached")
+	}
+	return r.readCommand(ctx, append(args, "--")...)
+}
+
+// GetChangedFiles returns the files changed since base, uncommitted changes
+// and untracked files included, relative to the checkout.
+func (r *Repository) GetChangedFiles(ctx context.Context, base string) ([]string, error) {
+	changed, err := r.readCommand(ctx, "diff", "--name-only", base, "--")
+	if err != nil {
+		return nil, err
+	}
+	untracked, err := r.readCommand(ctx, "ls-files", "--others", "--exclude-standard")
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for line := range strings.SplitSeq(changed+"\n"+untracked, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			files = append(files, line)
+		}
+	}
+	return files, nil
+}
+
+// FileChange is a line of `git status --porcelain`.
+type FileChange struct {
+	Index    string `json:"index"`
+	Worktree string `json:"worktree"`
+	Path     string `json:"path"`
+	OrigPath string `json:"orig_path,omitempty"`
+}
+
+func ParseStatusLine(line string) FileChange {
+	if len(line) < 4 {
+		return FileChange{Path: line}
+	}
+	change := FileChange{Index: line[0:1], Worktree: line[1:2], Path: line[3:]}
+	if origPath, path, ok := strings.Cut(change.Path, " -> "); ok {
+		change.OrigPath, change.Path = origPath, path
+	}
+	return change
 }
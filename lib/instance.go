@@ -0,0 +1,241 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultInstance is the instance run without a name, on the configured
+// odoo port.
+const DefaultInstance = "default"
+
+// Instance is an Odoo server run by odv, recorded in the instances registry of
+// the state directory so it can be listed, stopped and restarted later.
+type Instance struct {
+	Name       string    `json:"name"`
+	PID        int       `json:"pid"`
+	Port       int       `json:"port"`
+	GeventPort int       `json:"gevent_port"`
+	Branch     string    `json:"branch"`
+	Database   string    `json:"database"`
+	Args       []string  `json:"args"`
+	Detached   bool      `json:"detached"`
+	LogPath    string    `json:"log,omitempty"`
+	Started    time.Time `json:"started"`
+	StartTime  string    `json:"start_time,omitempty"` // of the process, as the system records it
+}
+
+const instancesFile = "instances.json"
+
+var ErrInstanceRunning = errors.New("instance already running")
+
+// updateInstances runs update on the registry, from which the instances that
+// are not running anymore have been removed, and saves it.
+func updateInstances(update func(instances map[string]*Instance) error) error {
+	stateLock.Lock()
+	defer stateLock.Unlock()
+	instances := make(map[string]*Instance)
+	if err := readStateFile(instancesFile, &instances); err != nil {
+		return err
+	}
+	maps.DeleteFunc(instances, func(_ string, instance *Instance) bool {
+		return !instance.isRunning()
+	})
+	if err := update(instances); err != nil {
+		return err
+	}
+	return writeStateFile(instancesFile, instances)
+}
+
+// GetInstances returns the running instances, sorted by name.
+func GetInstances() ([]*Instance, error) {
+	var instances []*Instance
+	err := updateInstances(func(registry map[string]*Instance) error {
+		for _, name := range slices.Sorted(maps.Keys(registry)) {
+			instances = append(instances, registry[name])
+		}
+		return nil
+	})
+	return instances, err
+}
+
+// GetInstance returns the running instance with the name, or nil.
+func GetInstance(name string) (*Instance, error) {
+	var instance *Instance
+	err := updateInstances(func(instances map[string]*Instance) error {
+		instance = instances[name]
+		return nil
+	})
+	return instance, err
+}
+
+// NewInstance prepares an instance that is not running yet. The default one
+// uses the configured odoo port (and odoo's gevent port offset), the named
+// ones get a free pair of ports from the configured range.
+func NewInstance(name, branch, dbName string, args []string) (*Instance, error) {
+	instance := &Instance{Name: name, Branch: branch, Database: dbName, Args: args}
+	err := updateInstances(func(instances map[string]*Instance) error {
+		if running, ok := instances[name]; ok {
+			return fmt.Errorf("'%s' (pid %d): %w", name, running.PID, ErrInstanceRunning)
+		}
+		if name == DefaultInstance {
+			instance.Port = GetConfig().OdooPort
+			instance.GeventPort = instance.Port + 3
+			return nil
+		}
+		var err error
+		instance.Port, instance.GeventPort, err = allocatePorts(instances)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return instance, nil
+}
+
+func allocatePorts(instances map[string]*Instance) (int, int, error) {
+	taken := map[int]bool{GetConfig().OdooPort: true, GetConfig().OdooPort + 3: true}
+	for _, instance := range instances {
+		taken[instance.Port] = true
+		taken[instance.GeventPort] = true
+	}
+	portRange := GetConfig().PortRange
+	for port := portRange[0]; port < portRange[1]; port++ {
+		if !taken[port] && !taken[port+1] && isPortFree(port) && isPortFree(port+1) {
+			return port, port + 1, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("no free ports left in the range %d-%d", portRange[0], portRange[1])
+}
+
+func isPortFree(port int) bool {
+	listener, err := net.Listen("tcp", ":"+strconv.Itoa(port))
+	if err != nil {
+		return false
+	}
+	listener.Close()
+	return true
+}
+
+// geventPortOption returns the odoo-bin option of the gevent port, which was
+// the longpolling port before 16.0.
+func geventPortOption(branch string) string {
+	major, err := strconv.Atoi(strings.SplitN(GetVersion(branch), ".", 2)[0])
+	if err == nil && major < 16 {
+		return "--longpolling-port="
+	}
+	return "--gevent-port="
+}
+
+// Command returns the odoo-bin command running the instance.
+func (instance *Instance) Command() (*exec.Cmd, error) {
+	args := []string{
+		"--http-port=" + strconv.Itoa(instance.Port),
+		geventPortOption(instance.Branch) + strconv.Itoa(instance.GeventPort),
+	}
+	return OdooCommand(instance.Database, append(args, instance.Args...)...)
+}
+
+// isRunning tells whether the process of the instance is still alive, and not
+// another one that got its pid after a crash or a reboot.
+func (instance *Instance) isRunning() bool {
+	if !IsProcessAlive(instance.PID) {
+		return false
+	}
+	startTime, ok := processStartTime(instance.PID)
+	return !ok || instance.StartTime == "" || startTime == instance.StartTime
+}
+
+// Register records the instance as running in the process.
+func (instance *Instance) Register(pid int) error {
+	instance.PID = pid
+	instance.Started = time.Now()
+	instance.StartTime, _ = processStartTime(pid)
+	return updateInstances(func(instances map[string]*Instance) error {
+		instances[instance.Name] = instance
+		return nil
+	})
+}
+
+func (instance *Instance) Unregister() error {
+	return updateInstances(func(instances map[string]*Instance) error {
+		if registered, ok := instances[instance.Name]; ok && registered.PID == instance.PID {
+			delete(instances, instance.Name)
+		}
+		return nil
+	})
+}
+
+// StartDetached starts the instance detached from the terminal, in its own
+// session so that stopping it reaches its workers too, with its output in a
+// log file of the state directory.
+func (instance *Instance) StartDetached() error {
+	cmd, err := instance.Command()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(GetStateDir(), 0o755); err != nil {
+		return err
+	}
+	instance.LogPath = filepath.Join(GetStateDir(), "odoo.log")
+	if instance.Name != DefaultInstance {
+		instance.LogPath = filepath.Join(GetStateDir(), "odoo-"+instance.Name+".log")
+	}
+	logFile, err := os.OpenFile(instance.LogPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	detachProcess(cmd)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start odoo-bin: %w", err)
+	}
+	instance.Detached = true
+	defer cmd.Process.Release()
+	return instance.Register(cmd.Process.Pid)
+}
+
+// Stop stops the instance, see StopProcess.
+func (instance *Instance) Stop() error {
+	if !instance.isRunning() {
+		return instance.Unregister()
+	}
+	pid := instance.PID
+	if instance.Detached {
+		// The instance leads its own process group, stop the workers with it.
+		pid = -pid
+	}
+	if err := StopProcess(pid); err != nil {
+		return err
+	}
+	return instance.Unregister()
+}
+
+// StopProcess asks the process (or process group, for a negative pid) to
+// terminate and kills it if it is still alive after the stop timeout, giving
+// Odoo the chance to finish its transactions.
+func StopProcess(pid int) error {
+	if err := terminateProcess(pid); err != nil {
+		return err
+	}
+	grace := time.Duration(GetConfig().Timeouts.Stop) * time.Second
+	for start := time.Now(); grace <= 0 || time.Since(start) < grace; {
+		if !IsProcessAlive(pid) {
+			return nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return killProcess(pid)
+}
@@ -0,0 +1,119 @@
+package lib
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	ClassName string         `xml:"classname,attr"`
+	Name      string         `xml:"name,attr"`
+	Time      string         `xml:"time,attr"`
+	Failures  []junitFailure `xml:"failure,omitempty"`
+	Errors    []junitFailure `xml:"error,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",cdata"`
+}
+
+func junitSeconds(seconds float64) string {
+	return fmt.Sprintf("%.3f", seconds)
+}
+
+// failureMessage returns the exception line following the last frame of the
+// traceback, the first one of the message when it spans several lines.
+func failureMessage(traceback string) string {
+	lines := strings.Split(strings.TrimSpace(traceback), "\n")
+	start := 0
+	for i, line := range lines {
+		if strings.HasPrefix(line, "  File ") {
+			start = i + 1
+		}
+	}
+	for _, line := range lines[start:] {
+		// The source line of the frame.
+		if !strings.HasPrefix(line, "    ") {
+			return strings.TrimSpace(line)
+		}
+	}
+	return ""
+}
+
+// WriteJUnit writes the tests of the report as JUnit XML, with a test suite
+// per module.
+func WriteJUnit(w io.Writer, report *TestReport) error {
+	suites := junitTestSuites{}
+	suiteIndex := make(map[string]int)
+	var suiteSeconds []float64
+	var total float64
+	for _, test := range report.Tests {
+		index, ok := suiteIndex[test.Module]
+		if !ok {
+			index = len(suites.Suites)
+			suiteIndex[test.Module] = index
+			suites.Suites = append(suites.Suites, junitTestSuite{Name: test.Module})
+			suiteSeconds = append(suiteSeconds, 0)
+		}
+		suite := &suites.Suites[index]
+
+		testCase := junitTestCase{ClassName: test.Class, Name: test.Method, Time: junitSeconds(test.Seconds)}
+		for _, failure := range test.Failures {
+			junit := junitFailure{Message: failureMessage(failure.Traceback), Type: failure.Kind, Text: failure.Traceback}
+			if failure.Kind == "FAIL" {
+				testCase.Failures = append(testCase.Failures, junit)
+			} else {
+				testCase.Errors = append(testCase.Errors, junit)
+			}
+		}
+		suite.Cases = append(suite.Cases, testCase)
+		suite.Tests++
+		if len(testCase.Failures) > 0 {
+			suite.Failures++
+		} else if len(testCase.Errors) > 0 {
+			suite.Errors++
+		}
+		suiteSeconds[index] += test.Seconds
+		total += test.Seconds
+	}
+	for i, suite := range suites.Suites {
+		suites.Suites[i].Time = junitSeconds(suiteSeconds[i])
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Errors += suite.Errors
+	}
+	suites.Time = junitSeconds(total)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
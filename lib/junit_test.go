@@ -0,0 +1,56 @@
+package lib
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "Rewrite the golden files with the current output.")
+
+func TestWriteJUnit(t *testing.T) {
+	report, err := ParseTestLog(strings.NewReader(readTestLog(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got bytes.Buffer
+	if err := WriteJUnit(&got, report); err != nil {
+		t.Fatal(err)
+	}
+
+	golden := filepath.Join("testdata", "odoo-test.xml")
+	if *update {
+		if err := os.WriteFile(golden, got.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got.Bytes(), want) {
+		t.Errorf("WriteJUnit output differs from %s:\n%s", golden, got.String())
+	}
+}
+
+func TestFailureMessage(t *testing.T) {
+	tests := []struct {
+		name      string
+		traceback string
+		want      string
+	}{
+		{"traceback", "Traceback (most recent call last):\n  File \"x.py\", line 1, in f\nAssertionError: 1 != 2\n", "AssertionError: 1 != 2"},
+		{"multiline message", "Traceback (most recent call last):\n  File \"x.py\", line 1, in f\n    self.assertEqual(a, b)\nAssertionError: 'a' != 'b'\n- a\n+ b\n", "AssertionError: 'a' != 'b'"},
+		{"empty traceback", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := failureMessage(tt.traceback); got != tt.want {
+				t.Errorf("failureMessage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// findModuleFile looks for a file inside a module in the addons paths, the
+// same way odoo.modules.get_module_resource does.
+func findModuleFile(module string, relPath string) (string, bool) {
+	for _, addonsPath := range GetAddonsPaths() {
+		filePath := filepath.Join(addonsPath, module, relPath)
+		if _, err := os.Stat(filePath); err == nil {
+			return filePath, true
+		}
+	}
+	return "", false
+}
+
+// checkCheckoutVersion makes sure the checked-out community matches the
+// series of the database, as neutralize.sql files differ between versions.
+func checkCheckoutVersion(ctx context.Context, conn *pgx.Conn, dbName string) error {
+	var baseVersion string
+	err := conn.QueryRow(ctx, "SELECT COALESCE(latest_version, '') FROM ir_module_module WHERE name = 'base'").Scan(&baseVersion)
+	if err != nil {
+		return newDBError("read version of", dbName, err)
+	}
+	dbVersion := strings.Replace(odooMajorVersion(baseVersion), "saas~", "saas-", 1)
+	checkoutVersion := DetectVersion(GetRepository("community").GetCurrentBranch(ctx))
+	if checkoutVersion != FallbackBranch && dbVersion != checkoutVersion {
+		return fmt.Errorf("database %s is on version %s but community is on %s", dbName, dbVersion, checkoutVersion)
+	}
+	return nil
+}
+
+// NeutralizeDB runs the data/neutralize.sql script of every installed module,
+// like odoo-bin neutralize does.
+func NeutralizeDB(ctx context.Context, dbName string) error {
+	ctx, cancel := withTimeout(ctx, GetConfig().Timeouts.DB)
+	defer cancel()
+	conn, err := connectDB(ctx, dbName)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(ctx)
+
+	if err := checkCheckoutVersion(ctx, conn, dbName); err != nil {
+		return err
+	}
+	rows, err := conn.Query(ctx, "SELECT name FROM ir_module_module WHERE state IN ('installed', 'to upgrade', 'to remove') ORDER BY name")
+	if err != nil {
+		return newDBError("read modules of", dbName, err)
+	}
+	modules, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return newDBError("read modules of", dbName, err)
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return newDBError("neutralize", dbName, err)
+	}
+	defer tx.Rollback(ctx)
+	for _, module := range modules {
+		scriptPath, ok := findModuleFile(module, filepath.Join("data", "neutralize.sql"))
+		if !ok {
+			continue
+		}
+		script, err := os.ReadFile(scriptPath)
+		if err != nil {
+			return fmt.Errorf("failed to read neutralization script of %s: %w", module, err)
+		}
+		if _, err := tx.Exec(ctx, strings.TrimSpace(string(script))); err != nil {
+			return newDBError("neutralize "+module+" in", dbName, err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return newDBError("neutralize", dbName, err)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package lib
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Directories (relative to each repository) that may hold Odoo addons, in
+// the order they should appear in --addons-path.
+var addonsSubdirs = []struct {
+	repo    string
+	subdirs []string
+}{
+	{"community", []string{"addons", filepath.Join("odoo", "addons")}},
+	{"enterprise", []string{"."}},
+	{".workspace", []string{".", "addons"}},
+}
+
+var dbNameInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+func isAddonsDir(path string) bool {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return false
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(path, entry.Name(), "__manifest__.py")); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func GetOdooBin() string {
+	return filepath.Join(GetRepository("community").Path(), "odoo-bin")
+}
+
+func GetAddonsPaths() []string {
+	var paths []string
+	for _, entry := range addonsSubdirs {
+		repo, exists := GetRepositories()[entry.repo]
+		if !exists {
+			continue
+		}
+		for _, subdir := range entry.subdirs {
+			path := filepath.Join(repo.Path(), subdir)
+			if isAddonsDir(path) {
+				paths = append(paths, path)
+			}
+		}
+	}
+	return paths
+}
+
+// HasAddons reports whether the repository holds addons directories.
+func HasAddons(repoName string) bool {
+	for _, entry := range addonsSubdirs {
+		if entry.repo == repoName {
+			return true
+		}
+	}
+	return false
+}
+
+// ModulesOfPaths returns the modules holding the files, given relative to
+// the checkout of the repository, that are in its addons directories.
+func ModulesOfPaths(repoName string, paths []string) []string {
+	repo, exists := GetRepositories()[repoName]
+	if !exists {
+		return nil
+	}
+	var modules []string
+	for _, entry := range addonsSubdirs {
+		if entry.repo != repoName {
+			continue
+		}
+		for _, subdir := range entry.subdirs {
+			for _, path := range paths {
+				rel, err := filepath.Rel(subdir, filepath.FromSlash(path))
+				if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+					continue
+				}
+				module, _, _ := strings.Cut(rel, string(filepath.Separator))
+				if module == rel || slices.Contains(modules, module) {
+					continue
+				}
+				if _, err := os.Stat(filepath.Join(repo.Path(), subdir, module, "__manifest__.py")); err == nil {
+					modules = append(modules, module)
+				}
+			}
+		}
+	}
+	return modules
+}
+
+func GetDefaultDBName(branch string) string {
+	return GetConfig().DBPrefix + dbNameInvalidChars.ReplaceAllString(branch, "-")
+}
+
+// GetTestDBName returns the throwaway database the tests of the branch run on.
+func GetTestDBName(branch string) string {
+	return GetConfig().DBPrefix + "test-" + dbNameInvalidChars.ReplaceAllString(branch, "-")
+}
+
+func OdooCommand(dbName string, extraArgs ...string) (*exec.Cmd, error) {
+	odooBin := GetOdooBin()
+	if _, err := os.Stat(odooBin); err != nil {
+		return nil, fmt.Errorf("odoo-bin not found: %w", err)
+	}
+	addonsPaths := GetAddonsPaths()
+	if len(addonsPaths) == 0 {
+		return nil, fmt.Errorf("no addons directories found in the configured repositories")
+	}
+
+	args := []string{
+		"--addons-path=" + strings.Join(addonsPaths, ","),
+		"-d", dbName,
+	}
+	return exec.Command(odooBin, append(args, extraArgs...)...), nil
+}
@@ -0,0 +1,94 @@
+//go:build linux
+
+package lib
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const tcpListen = "0A"
+
+// FindListeningPIDs returns the processes listening on the TCP port, by
+// matching the sockets of /proc/net/tcp{,6} with the file descriptors of the
+// processes (only the ones of the current user can be seen).
+func FindListeningPIDs(port int) ([]int, error) {
+	inodes := make(map[string]bool)
+	for _, table := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		if err := listeningInodes(table, port, inodes); err != nil {
+			return nil, err
+		}
+	}
+	if len(inodes) == 0 {
+		return nil, nil
+	}
+
+	fds, err := filepath.Glob("/proc/[0-9]*/fd/*")
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[int]bool)
+	var pids []int
+	for _, fd := range fds {
+		link, err := os.Readlink(fd)
+		if err != nil || !strings.HasPrefix(link, "socket:[") {
+			continue
+		}
+		if !inodes[strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]")] {
+			continue
+		}
+		pid, err := strconv.Atoi(strings.Split(fd, "/")[2])
+		if err == nil && !seen[pid] {
+			seen[pid] = true
+			pids = append(pids, pid)
+		}
+	}
+	return pids, nil
+}
+
+func listeningInodes(table string, port int, inodes map[string]bool) error {
+	file, err := os.Open(table)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	suffix := fmt.Sprintf(":%04X", port)
+	scanner := bufio.NewScanner(file)
+	scanner.Scan() // header
+	for scanner.Scan() {
+		// sl local_address rem_address st tx_queue:rx_queue tr:tm->when retrnsmt uid timeout inode
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 || fields[3] != tcpListen || !strings.HasSuffix(fields[1], suffix) {
+			continue
+		}
+		inodes[fields[9]] = true
+	}
+	return scanner.Err()
+}
+
+// processStartTime returns when the process started, in clock ticks since
+// boot, which tells it apart from a later process reusing its pid.
+func processStartTime(pid int) (string, bool) {
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return "", false
+	}
+	// The command name may hold spaces and parentheses, the fields after it
+	// start with the state, which is the third one.
+	end := strings.LastIndexByte(string(stat), ')')
+	if end < 0 {
+		return "", false
+	}
+	fields := strings.Fields(string(stat[end+1:]))
+	if len(fields) < 20 {
+		return "", false
+	}
+	return fields[19], true
+}
@@ -0,0 +1,41 @@
+//go:build !unix
+
+package lib
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+// detachProcess does nothing where there are no sessions to leave.
+func detachProcess(cmd *exec.Cmd) {}
+
+// IsProcessAlive tells whether the process exists, process groups (negative
+// pids) standing for their leader.
+func IsProcessAlive(pid int) bool {
+	process, err := os.FindProcess(max(pid, -pid))
+	if err != nil {
+		return false
+	}
+	process.Release()
+	return true
+}
+
+// terminateProcess kills the process, as there are no signals to ask it to
+// shut down.
+func terminateProcess(pid int) error {
+	return killProcess(pid)
+}
+
+func killProcess(pid int) error {
+	process, err := os.FindProcess(max(pid, -pid))
+	if err != nil {
+		return nil
+	}
+	defer process.Release()
+	if err := process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return err
+	}
+	return nil
+}
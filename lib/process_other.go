@@ -0,0 +1,41 @@
+//go:build !linux
+
+package lib
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// FindListeningPIDs returns the processes listening on the TCP port, using
+// lsof where there is no /proc to read.
+func FindListeningPIDs(port int) ([]int, error) {
+	output, err := exec.Command("lsof", "-t", "-sTCP:LISTEN", fmt.Sprintf("-iTCP:%d", port)).Output()
+	if err != nil {
+		// lsof exits with 1 when nothing matches.
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var pids []int
+	for line := range strings.FieldsSeq(string(output)) {
+		if pid, err := strconv.Atoi(line); err == nil {
+			pids = append(pids, pid)
+		}
+	}
+	return pids, nil
+}
+
+// processStartTime returns when the process started, which tells it apart
+// from a later process reusing its pid.
+func processStartTime(pid int) (string, bool) {
+	output, err := exec.Command("ps", "-o", "lstart=", "-p", strconv.Itoa(pid)).Output()
+	if err != nil {
+		return "", false
+	}
+	startTime := strings.TrimSpace(string(output))
+	return startTime, startTime != ""
+}
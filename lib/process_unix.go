@@ -0,0 +1,37 @@
+//go:build unix
+
+package lib
+
+import (
+	"errors"
+	"os/exec"
+	"syscall"
+)
+
+// detachProcess makes the command start in a session of its own, away from
+// the terminal and leading a process group with its workers.
+func detachProcess(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
+
+// IsProcessAlive tells whether the process (or process group, for a negative
+// pid) exists.
+func IsProcessAlive(pid int) bool {
+	return syscall.Kill(pid, 0) == nil
+}
+
+func signalProcess(pid int, signal syscall.Signal) error {
+	if err := syscall.Kill(pid, signal); err != nil && !errors.Is(err, syscall.ESRCH) {
+		return err
+	}
+	return nil
+}
+
+// terminateProcess sends SIGTERM, letting the process shut down.
+func terminateProcess(pid int) error {
+	return signalProcess(pid, syscall.SIGTERM)
+}
+
+func killProcess(pid int) error {
+	return signalProcess(pid, syscall.SIGKILL)
+}
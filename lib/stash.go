@@ -0,0 +1,74 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Work in progress stashes are the changes odv stashes when switching away
+// from a branch, to restore them when switching back. They are plain git
+// stashes, recognised by their message.
+const wipStashPrefix = "odv wip: "
+
+type WIPStash struct {
+	Ref     string    `json:"ref"`
+	Commit  string    `json:"commit"`
+	Branch  string    `json:"branch"`
+	Created time.Time `json:"created"`
+}
+
+// GetWIPStashes lists the work in progress stashes of the repository, newest first.
+func (r *Repository) GetWIPStashes(ctx context.Context) ([]WIPStash, error) {
+	output, err := r.readCommand(ctx, "stash", "list", "--format=%gd%x00%H%x00%ct%x00%s")
+	if err != nil {
+		return nil, err
+	}
+	var stashes []WIPStash
+	for line := range strings.SplitSeq(strings.TrimSpace(output), "\n") {
+		fields := strings.SplitN(line, "\x00", 4)
+		if len(fields) < 4 {
+			continue
+		}
+		// The subject is "On <branch>: <message>", branch names can't hold a colon.
+		_, message, _ := strings.Cut(fields[3], ": ")
+		branch, ok := strings.CutPrefix(message, wipStashPrefix)
+		if !ok {
+			continue
+		}
+		timestamp, _ := strconv.ParseInt(fields[2], 10, 64)
+		stashes = append(stashes, WIPStash{
+			Ref:     fields[0],
+			Commit:  fields[1],
+			Branch:  branch,
+			Created: time.Unix(timestamp, 0),
+		})
+	}
+	return stashes, nil
+}
+
+// StashWIP stashes all the local changes, untracked files included, as the
+// work in progress of the branch. It returns "" when there was nothing to stash.
+func (r *Repository) StashWIP(ctx context.Context, branch string) (string, error) {
+	return r.Stash(ctx, wipStashPrefix+branch, true)
+}
+
+// RestoreWIP pops the newest work in progress stash of the branch, returning
+// it, or nil when the branch has none.
+func (r *Repository) RestoreWIP(ctx context.Context, branch string) (*WIPStash, error) {
+	stashes, err := r.GetWIPStashes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, stash := range stashes {
+		if stash.Branch == branch {
+			if err := r.StashPop(ctx, stash.Commit); err != nil {
+				return &stash, fmt.Errorf("restoring %s conflicted: %w", stash.Ref, err)
+			}
+			return &stash, nil
+		}
+	}
+	return nil, nil
+}
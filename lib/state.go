@@ -0,0 +1,104 @@
+package lib
+
+import (
+	"encoding/json"
+	"maps"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+var (
+	stateDir     string
+	stateDirOnce sync.Once
+
+	stateLock sync.Mutex
+)
+
+// GetStateDir returns the directory where odv keeps the state it records
+// between runs ($XDG_STATE_HOME/odv, ~/.local/state/odv by default).
+func GetStateDir() string {
+	stateDirOnce.Do(func() {
+		base := os.Getenv("XDG_STATE_HOME")
+		if base == "" {
+			base = filepath.Join(GetUserHome(), ".local", "state")
+		}
+		stateDir = filepath.Join(base, "odv")
+	})
+	return stateDir
+}
+
+func readStateFile(name string, v any) error {
+	data, err := os.ReadFile(filepath.Join(GetStateDir(), name))
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func writeStateFile(name string, v any) error {
+	if err := os.MkdirAll(GetStateDir(), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Write to a temporary file first so a crash never leaves a truncated state file.
+	tmpPath := filepath.Join(GetStateDir(), name+".tmp")
+	if err := os.WriteFile(tmpPath, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, filepath.Join(GetStateDir(), name))
+}
+
+const branchDBsFile = "branch-dbs.json"
+
+func updateBranchDBs(update func(links map[string]string)) error {
+	stateLock.Lock()
+	defer stateLock.Unlock()
+	links := make(map[string]string)
+	if err := readStateFile(branchDBsFile, &links); err != nil {
+		return err
+	}
+	update(links)
+	return writeStateFile(branchDBsFile, links)
+}
+
+func GetLinkedDBs() map[string]string {
+	stateLock.Lock()
+	defer stateLock.Unlock()
+	links := make(map[string]string)
+	readStateFile(branchDBsFile, &links)
+	return links
+}
+
+func GetLinkedDB(branch string) (string, bool) {
+	dbName, ok := GetLinkedDBs()[branch]
+	return dbName, ok
+}
+
+// GetBranchDB returns the database linked to the branch, or the default
+// database name for it when there is none.
+func GetBranchDB(branch string) string {
+	if dbName, ok := GetLinkedDB(branch); ok {
+		return dbName
+	}
+	return GetDefaultDBName(branch)
+}
+
+func LinkDB(branch, dbName string) error {
+	return updateBranchDBs(func(links map[string]string) { links[branch] = dbName })
+}
+
+func UnlinkDB(branch string) error {
+	return updateBranchDBs(func(links map[string]string) { delete(links, branch) })
+}
+
+func forgetDB(dbName string) error {
+	return updateBranchDBs(func(links map[string]string) {
+		maps.DeleteFunc(links, func(_, linkedDB string) bool { return linkedDB == dbName })
+	})
+}
@@ -0,0 +1,167 @@
+package lib
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TestFailure is a test that failed (FAIL) or raised (ERROR) in an Odoo log.
+type TestFailure struct {
+	Kind      string `json:"kind"`
+	Module    string `json:"module"`
+	Test      string `json:"test"` // Class.method, with the subtest parameters if any
+	Traceback string `json:"traceback"`
+}
+
+// TestCase is a test found in an Odoo log, timed from its "Starting" line to
+// the start of the next one.
+type TestCase struct {
+	Module   string        `json:"module"`
+	Class    string        `json:"class"` // qualified with the python module of the test file
+	Method   string        `json:"method"`
+	Seconds  float64       `json:"seconds"`
+	Failures []TestFailure `json:"failures,omitempty"` // one per failed subtest
+}
+
+// TestReport is the outcome of the tests found in an Odoo log.
+type TestReport struct {
+	Failed     int           `json:"failed"`
+	Errors     int           `json:"errors"`
+	Total      int           `json:"total"`
+	HasSummary bool          `json:"has_summary"` // false when Odoo stopped before reporting
+	Failures   []TestFailure `json:"failures"`
+	Tests      []*TestCase   `json:"tests"`
+}
+
+func (report *TestReport) Passed() bool {
+	return report.HasSummary && report.Failed == 0 && report.Errors == 0
+}
+
+type logRecord struct {
+	time    time.Time
+	level   string
+	logger  string
+	message string // with its continuation lines, tracebacks above all
+}
+
+const logTimeLayout = "2006-01-02 15:04:05,000"
+
+var (
+	// 2024-01-01 12:00:00,123 4242 ERROR dbname odoo.addons.sale.tests.test_sale: message
+	logRecordHeader = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2},\d{3}) \d+ (\w+) \S+ ([\w.]+): (.*)$`)
+	testStartLine   = regexp.MustCompile(`^Starting (\w+)\.(\w+) \.\.\.$`)
+	testFailureLine = regexp.MustCompile(`^(FAIL|ERROR): (.+)$`)
+	testSummaryLine = regexp.MustCompile(`(\d+) failed, (\d+) error\(s\) of (\d+) tests`)
+)
+
+// scanLogRecords calls record for each record of the log, joining the lines
+// that do not start a record to the previous one.
+func scanLogRecords(r io.Reader, record func(logRecord)) error {
+	var current *logRecord
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if match := logRecordHeader.FindStringSubmatch(line); match != nil {
+			if current != nil {
+				record(*current)
+			}
+			recordTime, _ := time.Parse(logTimeLayout, match[1])
+			current = &logRecord{time: recordTime, level: match[2], logger: match[3], message: match[4]}
+		} else if current != nil {
+			current.message += "\n" + line
+		}
+	}
+	if current != nil {
+		record(*current)
+	}
+	return scanner.Err()
+}
+
+// moduleOfLogger returns the module of an odoo.addons.<module>... logger.
+func moduleOfLogger(logger string) string {
+	if rest, ok := strings.CutPrefix(logger, "odoo.addons."); ok {
+		return strings.SplitN(rest, ".", 2)[0]
+	}
+	return ""
+}
+
+// endsTest tells whether the record comes after the running test, as Odoo
+// moves on to loading modules or reporting the results.
+func endsTest(record logRecord) bool {
+	return strings.HasPrefix(record.logger, "odoo.modules.") ||
+		strings.HasPrefix(record.logger, "odoo.tests.") ||
+		strings.HasPrefix(record.logger, "odoo.service.")
+}
+
+// ParseTestLog collects the tests of an Odoo log, their failures and its
+// final "N failed, M error(s) of X tests" summary.
+func ParseTestLog(r io.Reader) (*TestReport, error) {
+	report := &TestReport{}
+	var running *TestCase
+	var runningSince, lastRecord time.Time
+	endRunning := func(at time.Time) {
+		if running != nil {
+			running.Seconds = at.Sub(runningSince).Seconds()
+			running = nil
+		}
+	}
+
+	err := scanLogRecords(r, func(record logRecord) {
+		lastRecord = record.time
+		firstLine, traceback, _ := strings.Cut(record.message, "\n")
+		if match := testStartLine.FindStringSubmatch(firstLine); match != nil && record.level == "INFO" {
+			endRunning(record.time)
+			running = &TestCase{
+				Module: moduleOfLogger(record.logger),
+				Class:  record.logger + "." + match[1],
+				Method: match[2],
+			}
+			runningSince = record.time
+			report.Tests = append(report.Tests, running)
+		} else if match := testFailureLine.FindStringSubmatch(firstLine); match != nil && record.level == "ERROR" {
+			failure := TestFailure{
+				Kind:      match[1],
+				Module:    moduleOfLogger(record.logger),
+				Test:      match[2],
+				Traceback: strings.TrimRight(traceback, "\n"),
+			}
+			report.Failures = append(report.Failures, failure)
+			report.addFailure(failure, record.logger)
+		} else if match := testSummaryLine.FindStringSubmatch(firstLine); match != nil {
+			report.Failed, _ = strconv.Atoi(match[1])
+			report.Errors, _ = strconv.Atoi(match[2])
+			report.Total, _ = strconv.Atoi(match[3])
+			report.HasSummary = true
+		}
+		if endsTest(record) {
+			endRunning(record.time)
+		}
+	})
+	endRunning(lastRecord)
+	return report, err
+}
+
+// addFailure attaches the failure to the last test it belongs to, or to a
+// test of its own for the failures outside of test methods, such as the ones
+// of setUpClass.
+func (report *TestReport) addFailure(failure TestFailure, logger string) {
+	for _, test := range slices.Backward(report.Tests) {
+		description := strings.TrimPrefix(test.Class, logger+".") + "." + test.Method
+		if test.Module == failure.Module && (failure.Test == description || strings.HasPrefix(failure.Test, description+" ")) {
+			test.Failures = append(test.Failures, failure)
+			return
+		}
+	}
+	report.Tests = append(report.Tests, &TestCase{
+		Module:   failure.Module,
+		Class:    logger,
+		Method:   failure.Test,
+		Failures: []TestFailure{failure},
+	})
+}
@@ -0,0 +1,105 @@
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// readTestLog returns the log of an Odoo test run with a passing test, a
+// failing one, one with failing subtests, a setUpClass error and an error
+// without traceback.
+func readTestLog(t *testing.T) string {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join("testdata", "odoo-test.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+// findTest returns the test of the report described as Class.method, or as
+// the method of the tests made up for the failures outside of test methods.
+func findTest(report *TestReport, description string) *TestCase {
+	for _, test := range report.Tests {
+		if test.Method == description || strings.HasSuffix(test.Class+"."+test.Method, "."+description) {
+			return test
+		}
+	}
+	return nil
+}
+
+func TestParseTestLog(t *testing.T) {
+	log := readTestLog(t)
+	// Odoo was stopped before logging its summary.
+	withoutSummary := log[:strings.LastIndex(strings.TrimRight(log, "\n"), "\n")+1]
+
+	tests := []struct {
+		name        string
+		log         string
+		test        string
+		wantKinds   []string
+		wantSeconds float64
+		wantSummary bool
+	}{
+		{"pass", log, "TestSaleOrder.test_confirm", nil, 0.5, true},
+		{"fail", log, "TestSaleOrder.test_cancel", []string{"FAIL"}, 0.3, true},
+		{"subtest", log, "TestSaleOrder.test_prices", []string{"FAIL", "FAIL"}, 0.6, true},
+		{"setUpClass error", log, "setUpClass (odoo.addons.sale.tests.test_sale_report.TestSaleReport)", []string{"ERROR"}, 0, true},
+		{"missing summary", withoutSummary, "TestAccountMove.test_post", []string{"ERROR"}, 0.8, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := ParseTestLog(strings.NewReader(tt.log))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.HasSummary != tt.wantSummary {
+				t.Errorf("HasSummary = %v, want %v", report.HasSummary, tt.wantSummary)
+			}
+			if report.Passed() {
+				t.Error("Passed() = true, want false")
+			}
+			test := findTest(report, tt.test)
+			if test == nil {
+				t.Fatalf("test %s not found", tt.test)
+			}
+			var kinds []string
+			for _, failure := range test.Failures {
+				kinds = append(kinds, failure.Kind)
+			}
+			if !slices.Equal(kinds, tt.wantKinds) {
+				t.Errorf("failure kinds = %v, want %v", kinds, tt.wantKinds)
+			}
+			if diff := test.Seconds - tt.wantSeconds; diff > 1e-9 || diff < -1e-9 {
+				t.Errorf("Seconds = %v, want %v", test.Seconds, tt.wantSeconds)
+			}
+		})
+	}
+}
+
+func TestParseTestLogSummary(t *testing.T) {
+	report, err := ParseTestLog(strings.NewReader(readTestLog(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Failed != 3 || report.Errors != 2 || report.Total != 5 {
+		t.Errorf("summary = %d failed, %d errors of %d, want 3 failed, 2 errors of 5", report.Failed, report.Errors, report.Total)
+	}
+	if len(report.Failures) != 5 {
+		t.Errorf("got %d failures, want 5", len(report.Failures))
+	}
+	if len(report.Tests) != 5 {
+		t.Errorf("got %d tests, want 5", len(report.Tests))
+	}
+	cancel := findTest(report, "TestSaleOrder.test_cancel")
+	if cancel == nil || len(cancel.Failures) != 1 {
+		t.Fatal("test_cancel failure not found")
+	}
+	traceback := cancel.Failures[0].Traceback
+	if !strings.HasPrefix(traceback, "Traceback (most recent call last):") || !strings.HasSuffix(traceback, "+ cancel") {
+		t.Errorf("unexpected traceback:\n%s", traceback)
+	}
+}
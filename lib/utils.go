@@ -1,12 +1,15 @@
 package lib
 
 import (
+	"context"
 	"fmt"
 	"maps"
+	"os"
 	"os/exec"
 	"path/filepath"
 	"slices"
 	"sync"
+	"time"
 )
 
 var (
@@ -23,14 +26,11 @@ func GetRepositories() map[string]*Repository {
 
 		for name, folderName := range cfg.Repositories {
 			fullPath := filepath.Join(cfg.OdooHome, folderName)
-			repositories[name] = &Repository{path: fullPath}
+			repositories[name] = &Repository{folder: folderName, root: fullPath, path: fullPath}
 		}
-
-		var wg sync.WaitGroup
-		for _, repo := range repositories {
-			wg.Go(func() { repo.GetBranches() })
+		if cfg.Worktrees {
+			loadActiveWorktrees(repositories)
 		}
-		wg.Wait()
 	})
 	return repositories
 }
@@ -58,23 +58,73 @@ func GetRepositoryByIndex(index int) *Repository {
 	return GetRepository(repoNames[index])
 }
 
-func GetAllBranches() []string {
-	var branches []string
+// GetCurrentBranch returns the branch being worked on: the first dev branch
+// checked out in the repositories, or community's branch when all of them
+// are on version branches.
+func GetCurrentBranch(ctx context.Context) string {
+	for _, repoName := range GetSortedRepoNames() {
+		branch := GetRepository(repoName).GetCurrentBranch(ctx)
+		if branch != "" && !IsVersionBranch(branch) {
+			return branch
+		}
+	}
+	return GetRepository("community").GetCurrentBranch(ctx)
+}
+
+func GetAllBranches(ctx context.Context) []string {
+	var wg sync.WaitGroup
 	for repoName, repo := range GetRepositories() {
 		if repoName == ".workspace" {
 			continue // skip as .workspace will create branches for everything.
 		}
-		for _, branch := range repo.GetBranches() {
-			branches = append(branches, branch)
+		wg.Go(func() { repo.GetBranches(ctx) })
+	}
+	wg.Wait()
+
+	var branches []string
+	for repoName, repo := range GetRepositories() {
+		if repoName == ".workspace" {
+			continue
+		}
+		branches = append(branches, repo.GetBranches(ctx)...)
+	}
+	SortBranches(branches)
+	return slices.Compact(branches)
+}
+
+// GetAllRemoteBranches returns the branches of the remote known in any
+// repository but .workspace.
+func GetAllRemoteBranches(ctx context.Context, remote string) []string {
+	var branches []string
+	for repoName, repo := range GetRepositories() {
+		if repoName == ".workspace" {
+			continue
 		}
+		branches = append(branches, repo.GetRemoteBranches(ctx, remote)...)
 	}
 	SortBranches(branches)
 	return slices.Compact(branches)
 }
 
-func runCommand(name string, args ...string) (string, error) {
-	cmd := exec.Command(name, args...)
+// withTimeout derives a context cancelled after the given number of seconds,
+// or only when the parent is if it is not positive.
+func withTimeout(ctx context.Context, seconds int) (context.Context, context.CancelFunc) {
+	if seconds <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, time.Duration(seconds)*time.Second)
+}
+
+func runCommand(ctx context.Context, name string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	// Never wait for a password prompt nobody can answer, and don't let
+	// children (e.g. ssh) holding the output open block a cancellation.
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	cmd.WaitDelay = time.Second
 	output, err := cmd.CombinedOutput()
+	if ctxErr := ctx.Err(); err != nil && ctxErr != nil {
+		return string(output), ctxErr
+	}
 	if err != nil {
 		err = fmt.Errorf("%w: %v", err, string(output))
 	}
@@ -0,0 +1,132 @@
+package lib
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// In worktree mode every dev branch gets its own checkout of the repository,
+// <worktree_dir>/<branch>/<repo>, so switching branches only changes which
+// checkouts are active. Version branches stay in the main checkout.
+
+const activeWorktreesFile = "worktrees.json"
+
+type activeWorktrees struct {
+	Branch string            `json:"branch"`
+	Paths  map[string]string `json:"paths"`
+}
+
+// loadActiveWorktrees points the repositories to the checkouts recorded by
+// the last switch, ignoring the ones that were removed since.
+func loadActiveWorktrees(repos map[string]*Repository) {
+	var active activeWorktrees
+	stateLock.Lock()
+	readStateFile(activeWorktreesFile, &active)
+	stateLock.Unlock()
+	for name, path := range active.Paths {
+		repo, ok := repos[name]
+		if !ok {
+			continue
+		}
+		if _, err := os.Stat(path); err == nil {
+			repo.path = path
+		}
+	}
+}
+
+// SaveActiveWorktrees records the active checkout of every repository as the
+// ones of the branch.
+func SaveActiveWorktrees(branch string) error {
+	active := activeWorktrees{Branch: branch, Paths: make(map[string]string)}
+	for name, repo := range GetRepositories() {
+		active.Paths[name] = repo.Path()
+	}
+	stateLock.Lock()
+	defer stateLock.Unlock()
+	return writeStateFile(activeWorktreesFile, active)
+}
+
+// SamePath tells whether both paths are the same directory, as git reports
+// absolute paths with symlinks resolved.
+func SamePath(a, b string) bool {
+	infoA, errA := os.Stat(a)
+	infoB, errB := os.Stat(b)
+	return errA == nil && errB == nil && os.SameFile(infoA, infoB)
+}
+
+// Root returns the main checkout of the repository.
+func (r *Repository) Root() string {
+	return r.root
+}
+
+func (r *Repository) SetActivePath(path string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.path = path
+}
+
+// WorktreePath returns where the worktree of the branch goes.
+func (r *Repository) WorktreePath(branch string) string {
+	return filepath.Join(GetConfig().WorktreeDir, branch, r.folder)
+}
+
+// GetWorktrees maps the branches checked out in the repository, the main
+// checkout included, to the path of their checkout.
+func (r *Repository) GetWorktrees(ctx context.Context) (map[string]string, error) {
+	output, err := r.readCommand(ctx, "worktree", "list", "--porcelain")
+	if err != nil {
+		return nil, err
+	}
+	worktrees := make(map[string]string)
+	var path string
+	for line := range strings.SplitSeq(output, "\n") {
+		if value, ok := strings.CutPrefix(line, "worktree "); ok {
+			path = value
+		} else if value, ok := strings.CutPrefix(line, "branch refs/heads/"); ok {
+			worktrees[value] = path
+		}
+	}
+	return worktrees, nil
+}
+
+// AddWorktree checks the branch out in its worktree, creating the branch from
+// base first when base is not empty, and returns the worktree path.
+func (r *Repository) AddWorktree(ctx context.Context, branch, base string) (string, error) {
+	path := r.WorktreePath(branch)
+	args := []string{"worktree", "add", path, branch}
+	if base != "" {
+		args = []string{"worktree", "add", "-b", branch, path, base}
+	}
+	if err := r.writeCommand(ctx, args...); err != nil {
+		return "", err
+	}
+	if base != "" {
+		r.getBranchesOnce = sync.Once{} // reset so branches will be reloaded on next GetBranches call
+	}
+	return path, nil
+}
+
+// RemoveWorktree removes the worktree of the branch, if it has one. The main
+// checkout is never removed, and becomes the active one again if needed.
+func (r *Repository) RemoveWorktree(ctx context.Context, branch string) error {
+	worktrees, err := r.GetWorktrees(ctx)
+	if err != nil {
+		return err
+	}
+	path, ok := worktrees[branch]
+	if !ok || SamePath(path, r.root) {
+		return nil
+	}
+	if SamePath(r.Path(), path) {
+		r.SetActivePath(r.root)
+	}
+	if err := r.writeCommand(ctx, "worktree", "remove", path); err != nil {
+		return err
+	}
+	// Remove <worktree_dir>/<branch> once the last repository left it.
+	os.Remove(filepath.Dir(path))
+	return nil
+}
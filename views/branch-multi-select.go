@@ -0,0 +1,128 @@
+package views
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type BranchChoice struct {
+	Branch   string
+	Detail   string // rendered after the branch name
+	Selected bool
+}
+
+// BranchMultiSelectView lets the user pick any number of branches, returning
+// the chosen ones (an empty slice when none), or nil when cancelled.
+type BranchMultiSelectView struct {
+	Title   string
+	Choices []BranchChoice
+}
+
+func (cfg BranchMultiSelectView) Run() ([]string, error) {
+	if !IsInteractive() {
+		return nil, ErrNotInteractive
+	}
+	m := branchMultiSelectModel{title: cfg.Title, choices: cfg.Choices}
+	p := tea.NewProgram(m, tea.WithAltScreen())
+	finalModel, err := p.Run()
+	if err != nil {
+		return nil, fmt.Errorf("error running program: %w", err)
+	}
+
+	fm, ok := finalModel.(branchMultiSelectModel)
+	if !ok || !fm.confirmed {
+		return nil, nil
+	}
+	selected := []string{}
+	for _, choice := range fm.choices {
+		if choice.Selected {
+			selected = append(selected, choice.Branch)
+		}
+	}
+	return selected, nil
+}
+
+// Bubbletea model
+
+type branchMultiSelectModel struct {
+	title     string
+	choices   []BranchChoice
+	cursor    int
+	offset    int
+	height    int
+	confirmed bool
+}
+
+func (m branchMultiSelectModel) Init() tea.Cmd { return nil }
+
+func (m branchMultiSelectModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		if msg.Height > 4 {
+			m.height = msg.Height - 4
+		}
+		return m, nil
+
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c", "q", "esc":
+			return m, tea.Quit
+		case "enter":
+			m.confirmed = true
+			return m, tea.Quit
+		case "up", "k":
+			m.cursor = max(m.cursor-1, 0)
+		case "down", "j":
+			m.cursor = min(m.cursor+1, max(len(m.choices)-1, 0))
+		case " ", "x":
+			if len(m.choices) > 0 {
+				m.choices[m.cursor].Selected = !m.choices[m.cursor].Selected
+			}
+		case "a":
+			allSelected := true
+			for _, choice := range m.choices {
+				allSelected = allSelected && choice.Selected
+			}
+			for i := range m.choices {
+				m.choices[i].Selected = !allSelected
+			}
+		}
+	}
+
+	// Keep the cursor on screen.
+	if m.height > 0 {
+		m.offset = min(m.offset, m.cursor)
+		m.offset = max(m.offset, m.cursor-m.height+1)
+	}
+	return m, nil
+}
+
+func (m branchMultiSelectModel) View() string {
+	if m.confirmed {
+		return ""
+	}
+	var b strings.Builder
+	fmt.Fprintln(&b, ListTitleStyle.Render(m.title))
+
+	end := len(m.choices)
+	if m.height > 0 {
+		end = min(end, m.offset+m.height)
+	}
+	for i := m.offset; i < end; i++ {
+		choice := m.choices[i]
+		box := "[ ]"
+		if choice.Selected {
+			box = "[x]"
+		}
+		line := fmt.Sprintf("%s %s %s", box, choice.Branch, choice.Detail)
+		if i == m.cursor {
+			fmt.Fprintln(&b, ListSelectedItemStyle.Render("→ "+line))
+		} else {
+			fmt.Fprintln(&b, ListItemStyle.Render(line))
+		}
+	}
+	fmt.Fprint(&b, ListHelpStyle.Render("space select • a select all • enter confirm • q cancel"))
+	return b.String()
+}
@@ -95,6 +95,9 @@ type BranchSelectListView struct {
 }
 
 func (cfg BranchSelectListView) Run() (string, error) {
+	if !IsInteractive() {
+		return "", ErrNotInteractive
+	}
 	const defaultWidth = 80
 	const defaultHeight = 20
 
@@ -0,0 +1,261 @@
+package views
+
+import (
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Conflict is a conflicted file, or a repository still rebasing without
+// conflicted files left when Path is empty.
+type Conflict struct {
+	Repo   string
+	Status string
+	Path   string
+}
+
+// ConflictResolverView lists the conflicted files of all repositories and
+// lets the user resolve them one by one. The git work is done by the callbacks.
+type ConflictResolverView struct {
+	Title          string
+	ListConflicts  func() ([]Conflict, error)
+	EditCommand    func(c Conflict) *exec.Cmd
+	Diff           func(c Conflict) (string, error)
+	MarkResolved   func(c Conflict) error
+	TakeSide       func(c Conflict, theirs bool) error
+	ContinueRebase func(repo string) error
+}
+
+func (cfg ConflictResolverView) Run() error {
+	if !IsInteractive() {
+		return ErrNotInteractive
+	}
+	conflicts, err := cfg.ListConflicts()
+	if err != nil {
+		return err
+	}
+	m := conflictResolverModel{config: cfg, diff: viewport.New(80, 20)}
+	m.setConflicts(conflicts)
+
+	p := tea.NewProgram(m, tea.WithAltScreen())
+	if _, err := p.Run(); err != nil {
+		return fmt.Errorf("error running program: %w", err)
+	}
+	return nil
+}
+
+// Bubbletea model
+
+type conflictItem struct {
+	Conflict
+	resolved bool
+}
+
+type editorClosedMsg struct {
+	err error
+}
+
+type conflictResolverModel struct {
+	config      ConflictResolverView
+	items       []conflictItem
+	cursor      int
+	showingDiff bool
+	diff        viewport.Model
+	message     string
+	isError     bool
+}
+
+func (m *conflictResolverModel) setConflicts(conflicts []Conflict) {
+	m.items = make([]conflictItem, len(conflicts))
+	for i, c := range conflicts {
+		m.items[i] = conflictItem{Conflict: c, resolved: c.Path == ""}
+	}
+	m.cursor = min(m.cursor, max(len(m.items)-1, 0))
+}
+
+func (m *conflictResolverModel) setMessage(err error, format string, a ...any) {
+	if err != nil {
+		m.message, m.isError = err.Error(), true
+	} else {
+		m.message, m.isError = fmt.Sprintf(format, a...), false
+	}
+}
+
+func (m *conflictResolverModel) reload() {
+	conflicts, err := m.config.ListConflicts()
+	if err != nil {
+		m.setMessage(err, "")
+		return
+	}
+	m.setConflicts(conflicts)
+}
+
+func (m conflictResolverModel) unresolvedIn(repo string) int {
+	count := 0
+	for _, item := range m.items {
+		if item.Repo == repo && !item.resolved {
+			count++
+		}
+	}
+	return count
+}
+
+func (m conflictResolverModel) Init() tea.Cmd { return nil }
+
+func (m conflictResolverModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.diff.Width = msg.Width
+		m.diff.Height = max(msg.Height-4, 1)
+		return m, nil
+
+	case editorClosedMsg:
+		m.setMessage(msg.err, "Editor closed, press 'a' to mark the file as resolved.")
+		return m, nil
+
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			return m, tea.Quit
+		}
+		if m.showingDiff {
+			switch msg.String() {
+			case "q", "esc", "d":
+				m.showingDiff = false
+				return m, nil
+			}
+			var cmd tea.Cmd
+			m.diff, cmd = m.diff.Update(msg)
+			return m, cmd
+		}
+		return m.handleKey(msg.String())
+	}
+	return m, nil
+}
+
+func (m conflictResolverModel) handleKey(key string) (tea.Model, tea.Cmd) {
+	switch key {
+	case "q", "esc":
+		return m, tea.Quit
+	case "up", "k":
+		m.cursor = max(m.cursor-1, 0)
+		return m, nil
+	case "down", "j":
+		m.cursor = min(m.cursor+1, max(len(m.items)-1, 0))
+		return m, nil
+	case "r":
+		m.reload()
+		return m, nil
+	}
+	if len(m.items) == 0 {
+		return m, nil
+	}
+
+	item := &m.items[m.cursor]
+	if item.Path == "" && slices.Contains([]string{"enter", "e", "d", "a", "o", "t"}, key) {
+		m.setMessage(nil, "No conflicted files left in %s, press 'c' to continue the rebase.", item.Repo)
+		return m, nil
+	}
+	switch key {
+	case "enter", "e":
+		return m, tea.ExecProcess(m.config.EditCommand(item.Conflict), func(err error) tea.Msg {
+			return editorClosedMsg{err: err}
+		})
+	case "d":
+		diff, err := m.config.Diff(item.Conflict)
+		if err != nil {
+			m.setMessage(err, "")
+			return m, nil
+		}
+		m.diff.SetContent(colorizeDiff(diff))
+		m.diff.GotoTop()
+		m.showingDiff = true
+	case "a":
+		err := m.config.MarkResolved(item.Conflict)
+		item.resolved = item.resolved || err == nil
+		m.setMessage(err, "Marked %s as resolved.", item.Path)
+	case "o", "t":
+		theirs := key == "t"
+		err := m.config.TakeSide(item.Conflict, theirs)
+		item.resolved = item.resolved || err == nil
+		side := "ours"
+		if theirs {
+			side = "theirs"
+		}
+		m.setMessage(err, "Took %s for %s.", side, item.Path)
+	case "c":
+		repo := item.Repo
+		if count := m.unresolvedIn(repo); count > 0 {
+			m.setMessage(fmt.Errorf("%d file(s) still unresolved in %s", count, repo), "")
+			return m, nil
+		}
+		err := m.config.ContinueRebase(repo)
+		m.reload()
+		m.setMessage(err, "Continued the rebase in %s.", repo)
+	}
+	return m, nil
+}
+
+func colorizeDiff(diff string) string {
+	lines := strings.Split(diff, "\n")
+	for i, line := range lines {
+		// Conflict diffs are combined diffs, with one marker column per side.
+		prefix := line[:min(2, len(line))]
+		switch {
+		case strings.HasPrefix(line, "@@"):
+			lines[i] = InfoStyle.Render(line)
+		case strings.Contains(prefix, "+"):
+			lines[i] = DiffAddedStyle.Render(line)
+		case strings.Contains(prefix, "-"):
+			lines[i] = DiffDeletedStyle.Render(line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (m conflictResolverModel) View() string {
+	if m.showingDiff {
+		item := m.items[m.cursor]
+		return fmt.Sprintf("%s %s\n%s\n%s",
+			RenderRepoName(item.Repo),
+			BoldStyle.Render(item.Path),
+			m.diff.View(),
+			ListHelpStyle.Render("↑/↓ scroll • d/esc back"))
+	}
+
+	var b strings.Builder
+	fmt.Fprintln(&b, HeaderStyle.Render(m.config.Title))
+	if len(m.items) == 0 {
+		fmt.Fprintln(&b, FaintStyle.Render("  No conflicts left."))
+	}
+	for i, item := range m.items {
+		indicator := ColorizeStatusIndicator(item.Status)
+		if item.resolved {
+			indicator = Checkmark + " "
+		}
+		path := item.Path
+		if path == "" {
+			path = FaintStyle.Render("all files resolved, rebase to continue")
+		}
+		line := fmt.Sprintf("%s %s %s", indicator, RenderRepoName(item.Repo), path)
+		if i == m.cursor {
+			fmt.Fprintln(&b, ListSelectedItemStyle.Render("→ "+line))
+		} else {
+			fmt.Fprintln(&b, ListItemStyle.Render(line))
+		}
+	}
+
+	fmt.Fprintln(&b)
+	if m.message != "" {
+		style := InfoStyle
+		if m.isError {
+			style = ErrorStyle
+		}
+		fmt.Fprintln(&b, style.Render(m.message))
+	}
+	fmt.Fprint(&b, ListHelpStyle.Render("e edit • d diff • a mark resolved • o take ours (base) • t take theirs (your commit) • c continue rebase • r refresh • q quit"))
+	return b.String()
+}
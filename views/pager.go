@@ -0,0 +1,31 @@
+package views
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Page shows the content through $PAGER (less -R by default) when running in
+// a terminal, and writes it to w otherwise.
+func Page(w io.Writer, content string) error {
+	if !IsInteractive() {
+		_, err := fmt.Fprint(w, content)
+		return err
+	}
+	args := strings.Fields(os.Getenv("PAGER"))
+	if len(args) == 0 {
+		args = []string{"less", "-R"}
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = strings.NewReader(content)
+	cmd.Stdout = w
+	cmd.Stderr = os.Stderr
+	if os.Getenv("LESS") == "" {
+		// Quit right away when it fits on one screen, like git does.
+		cmd.Env = append(os.Environ(), "LESS=FRX")
+	}
+	return cmd.Run()
+}
@@ -1,6 +1,8 @@
 package views
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -16,6 +18,8 @@ const (
 	StatusInProgress
 	StatusDone
 	StatusFailed
+	StatusCancelled
+	StatusTimedOut
 )
 
 type RepoOperationState struct {
@@ -69,26 +73,94 @@ func (state *RepoOperationState) RenderFailed(message string) string {
 		ErrorStyle.Render(fmt.Sprintf("Error: %v", state.Err)))
 }
 
+func (state *RepoOperationState) RenderCancelled() string {
+	return fmt.Sprintf("%s %s - %s\n",
+		FaintStyle.Render("⊘"),
+		RenderRepoName(state.Name),
+		WarningStyle.Render("cancelled"))
+}
+
+func (state *RepoOperationState) RenderTimedOut() string {
+	return fmt.Sprintf("%s %s - %s (%s)\n",
+		Cross,
+		RenderRepoName(state.Name),
+		ErrorStyle.Render("timed out"),
+		FaintStyle.Render(state.Duration.Round(time.Millisecond).String()))
+}
+
+// RepoBranchSpinnerView runs an operation on every repository in parallel.
+// The context given to LaunchOp and OnMsg is cancelled when the user presses
+// ctrl+c or q; operations that stop because of it (or of a timeout) are
+// rendered by the view itself instead of RenderRepo, followed by RenderNote
+// when set, which tells what the operation left behind.
 type RepoBranchSpinnerView struct {
 	Title          string
 	States         []*RepoOperationState
 	SkippedIndices map[int]bool
-	LaunchOp       func(i int) tea.Cmd
-	OnMsg          func(msg tea.Msg, states []*RepoOperationState) tea.Cmd
+	LaunchOp       func(ctx context.Context, i int) tea.Cmd
+	OnMsg          func(ctx context.Context, msg tea.Msg, states []*RepoOperationState) tea.Cmd
 	RenderRepo     func(i int, state *RepoOperationState) string
+	RenderNote     func(i int) string
+}
+
+// applyDoneMsg records the outcome of an operation, returning whether it failed.
+func (state *RepoOperationState) applyDoneMsg(msg RepoOperationDoneMsg) (failed bool) {
+	state.Duration = msg.Duration
+	state.Err = msg.Err
+	switch {
+	case errors.Is(msg.Err, context.Canceled):
+		state.Status = StatusCancelled
+	case errors.Is(msg.Err, context.DeadlineExceeded):
+		state.Status = StatusTimedOut
+	case msg.Err != nil:
+		state.Status = StatusFailed
+	default:
+		state.Status = StatusDone
+	}
+	return msg.Err != nil
+}
+
+func (cfg RepoBranchSpinnerView) renderRepo(i int) string {
+	state := cfg.States[i]
+	switch state.Status {
+	case StatusCancelled:
+		return state.RenderCancelled() + cfg.renderNote(i)
+	case StatusTimedOut:
+		return state.RenderTimedOut() + cfg.renderNote(i)
+	default:
+		return cfg.RenderRepo(i, state)
+	}
+}
+
+func (cfg RepoBranchSpinnerView) renderNote(i int) string {
+	if cfg.RenderNote == nil {
+		return ""
+	}
+	return cfg.RenderNote(i)
+}
+
+func renderFailureSummary(failCount int) string {
+	return WarningStyle.Render(fmt.Sprintf("⚠ %d operation(s) failed", failCount)) + "\n"
 }
 
-func (cfg RepoBranchSpinnerView) Run() (failCount int, err error) {
+func (cfg RepoBranchSpinnerView) Run(ctx context.Context) (failCount int, err error) {
 	activeCount := len(cfg.States)
 	for range cfg.SkippedIndices {
 		activeCount--
 	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if !IsInteractive() {
+		return cfg.runWithoutTUI(ctx), nil
+	}
 	p := tea.NewProgram(repoBranchSpinnerModel{
 		totalRepos:     activeCount,
 		startTime:      time.Now(),
 		states:         cfg.States,
 		skippedIndices: cfg.SkippedIndices,
 		config:         cfg,
+		ctx:            ctx,
+		cancel:         cancel,
 	})
 	finalModel, err := p.Run()
 	if err != nil {
@@ -110,6 +182,9 @@ type repoBranchSpinnerModel struct {
 	states         []*RepoOperationState
 	skippedIndices map[int]bool
 	config         RepoBranchSpinnerView
+	ctx            context.Context
+	cancel         context.CancelFunc
+	cancelling     bool
 }
 
 func (m repoBranchSpinnerModel) Init() tea.Cmd {
@@ -126,7 +201,7 @@ func (m repoBranchSpinnerModel) Init() tea.Cmd {
 		}
 		m.states[i].Status = StatusInProgress
 		m.states[i].StartTime = time.Now()
-		cmds = append(cmds, m.config.LaunchOp(i))
+		cmds = append(cmds, m.config.LaunchOp(m.ctx, i))
 	}
 	return tea.Batch(cmds...)
 }
@@ -134,14 +209,8 @@ func (m repoBranchSpinnerModel) Init() tea.Cmd {
 func (m repoBranchSpinnerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case RepoOperationDoneMsg:
-		state := m.states[msg.RepoIndex]
-		state.Duration = msg.Duration
-		if msg.Err != nil {
-			state.Status = StatusFailed
-			state.Err = msg.Err
+		if m.states[msg.RepoIndex].applyDoneMsg(msg) {
 			m.failCount++
-		} else {
-			state.Status = StatusDone
 		}
 		m.doneCount++
 		if m.doneCount >= m.totalRepos {
@@ -149,6 +218,15 @@ func (m repoBranchSpinnerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		return m, nil
 
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c", "q", "esc":
+			// Running operations stop on their own and report back as cancelled.
+			m.cancelling = true
+			m.cancel()
+		}
+		return m, nil
+
 	case spinner.TickMsg:
 		var cmds []tea.Cmd
 		for i := range m.states {
@@ -162,7 +240,7 @@ func (m repoBranchSpinnerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	default:
 		if m.config.OnMsg != nil {
-			if cmd := m.config.OnMsg(msg, m.states); cmd != nil {
+			if cmd := m.config.OnMsg(m.ctx, msg, m.states); cmd != nil {
 				return m, cmd
 			}
 		}
@@ -174,7 +252,11 @@ func (m repoBranchSpinnerModel) View() string {
 	var b strings.Builder
 
 	// Header
-	if m.doneCount < m.totalRepos {
+	if m.cancelling && m.doneCount < m.totalRepos {
+		fmt.Fprintf(&b, "%s Progress: %d/%d complete\n",
+			WarningStyle.Render("Cancelling "+strings.ToLower(m.config.Title)+"..."),
+			m.doneCount, m.totalRepos)
+	} else if m.doneCount < m.totalRepos {
 		fmt.Fprintf(&b, "%s Progress: %d/%d complete\n",
 			HeaderStyle.Render(m.config.Title+"..."),
 			m.doneCount, m.totalRepos)
@@ -185,14 +267,71 @@ func (m repoBranchSpinnerModel) View() string {
 	}
 
 	// Repo lines
-	for i, state := range m.states {
-		fmt.Fprint(&b, m.config.RenderRepo(i, state))
+	for i := range m.states {
+		fmt.Fprint(&b, m.config.renderRepo(i))
 	}
 
 	// Failure summary
 	if m.failCount > 0 {
-		fmt.Fprint(&b, WarningStyle.Render(fmt.Sprintf("⚠ %d operation(s) failed", m.failCount))+"\n")
+		fmt.Fprint(&b, renderFailureSummary(m.failCount))
 	}
 
 	return b.String()
 }
+
+// Line based fallback
+
+// runWithoutTUI runs the operations like the bubbletea program does, but
+// prints each repository line once it is final, for logs and pipes.
+func (cfg RepoBranchSpinnerView) runWithoutTUI(ctx context.Context) (failCount int) {
+	startTime := time.Now()
+	fmt.Println(HeaderStyle.Render(cfg.Title + "..."))
+
+	msgs := make(chan tea.Msg)
+	pending := 0
+	run := func(cmd tea.Cmd) {
+		if cmd == nil {
+			return
+		}
+		pending++
+		go func() { msgs <- cmd() }()
+	}
+
+	for i, state := range cfg.States {
+		if cfg.SkippedIndices[i] {
+			fmt.Print(cfg.renderRepo(i))
+			continue
+		}
+		state.Status = StatusInProgress
+		state.StartTime = time.Now()
+		run(cfg.LaunchOp(ctx, i))
+	}
+
+	for pending > 0 {
+		msg := <-msgs
+		pending--
+		switch msg := msg.(type) {
+		case RepoOperationDoneMsg:
+			if cfg.States[msg.RepoIndex].applyDoneMsg(msg) {
+				failCount++
+			}
+			fmt.Print(cfg.renderRepo(msg.RepoIndex))
+		case tea.BatchMsg:
+			for _, cmd := range msg {
+				run(cmd)
+			}
+		default:
+			if cfg.OnMsg != nil {
+				run(cfg.OnMsg(ctx, msg, cfg.States))
+			}
+		}
+	}
+
+	fmt.Printf("%s Completed in %s\n",
+		HeaderStyle.Render("✓ "+cfg.Title+" complete!"),
+		time.Since(startTime).Round(time.Millisecond))
+	if failCount > 0 {
+		fmt.Print(renderFailureSummary(failCount))
+	}
+	return failCount
+}
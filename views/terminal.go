@@ -0,0 +1,23 @@
+package views
+
+import (
+	"errors"
+	"os"
+
+	"github.com/mattn/go-isatty"
+)
+
+// NoTUI disables the bubbletea views even when running in a terminal.
+var NoTUI bool
+
+var ErrNotInteractive = errors.New("not running in an interactive terminal")
+
+// IsInteractive tells whether the views can take over the terminal. When they
+// can't, they fall back to line based output (or fail, for pickers).
+func IsInteractive() bool {
+	if NoTUI {
+		return false
+	}
+	fd := os.Stdout.Fd()
+	return isatty.IsTerminal(fd) || isatty.IsCygwinTerminal(fd)
+}